@@ -7,6 +7,12 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mchmarny/momd/pkg/menu"
 	"github.com/mchmarny/momd/pkg/server"
@@ -15,32 +21,124 @@ import (
 var (
 	version = "v0.0.0" // Set at build time via -ldflags "-X main.version=version"
 
-	port = flag.Int("port", server.DefaultPort, "Port to run the server on")
+	port   = flag.Int("port", server.DefaultPort, "Port to run the server on")
+	host   = flag.String("host", "127.0.0.1", "Host to listen on: an IP address, \"localhost\" for both IPv4 and IPv6 loopback, or empty for all interfaces")
+	socket = flag.String("socket", "", "Path of a Unix domain socket to listen on instead of a TCP port (mode 0600)")
+	config = flag.String("config", "", "Path to a YAML or JSON menu file (uses the built-in menu if not set)")
+	watch  = flag.Duration("watch", menu.DefaultWatchInterval, "How often to check the config file for changes (0 disables reloading)")
+
+	metrics = flag.Bool("metrics", false, "Serve Prometheus metrics at "+server.MetricsPath)
+
+	token     = flag.String("token", "", "Token clients must send as \"Authorization: Bearer <token>\" (defaults to $"+server.TokenEnvVar+", then the token file)")
+	tokenFile = flag.String("token-file", defaultTokenFile(), "File holding the token, generated with mode 0600 if it does not exist")
 )
 
+// list collects the values of a repeated flag.
+type list []string
+
+func (a *list) String() string {
+	return strings.Join(*a, ",")
+}
+
+func (a *list) Set(v string) error {
+	*a = append(*a, v)
+	return nil
+}
+
 func main() {
 	// Parse command-line flags
+	var listen, allowed list
+	flag.Var(&listen, "address", "Address to listen on in host:port form (e.g. [::1]:9876), can be repeated; overrides -host and -port")
+	flag.Var(&allowed, "allow-host", "Host name clients may use to reach the server besides the loopback ones (e.g. mac.local), can be repeated")
 	flag.Parse()
 
 	// Build the menu and its items
-	m := makeMenu()
+	m, err := buildMenu()
+	if err != nil {
+		slog.Error("failed to build menu", "error", err)
+		os.Exit(1)
+	}
 
-	ctx := context.Background()
+	auth, err := authToken()
+	if err != nil {
+		slog.Error("failed to load auth token", "error", err)
+		os.Exit(1)
+	}
+
+	// Shut down gracefully on interrupt so that listeners and jobs are cleaned up
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Reload the menu when the config file changes or on SIGHUP
+	if *config != "" && *watch > 0 {
+		go m.WatchFile(ctx, *config, registry(), *watch)
+	}
 
 	// Run the menu server
-	if err := m.Run(ctx, server.WithPort(*port)); err != nil {
+	opts := []server.Option{
+		server.WithHost(*host),
+		server.WithPort(*port),
+		server.WithAddress(listen...),
+		server.WithAuthToken(auth),
+		server.WithHostCheck(allowed...),
+	}
+	if *socket != "" {
+		opts = append(opts, server.WithUnixSocket(*socket, server.DefaultSocketMode))
+	}
+	if *metrics {
+		opts = append(opts, server.WithPrometheusMetrics())
+	}
+
+	if err := m.Run(ctx, opts...); err != nil {
 		slog.Error("server error", "error", err)
 	}
 }
 
+// authToken returns the token clients must send, from the -token flag, the environment or the token file.
+func authToken() (string, error) {
+	if *token != "" {
+		return *token, nil
+	}
+
+	if t := os.Getenv(server.TokenEnvVar); t != "" {
+		return t, nil
+	}
+
+	return server.LoadOrCreateToken(*tokenFile)
+}
+
+// defaultTokenFile returns the path of the token file in the user configuration directory.
+func defaultTokenFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "momd", "token")
+}
+
+// buildMenu returns the menu defined in the config file if one is provided, or the built-in menu.
+func buildMenu() (*menu.Menu, error) {
+	if *config == "" {
+		return makeMenu(), nil
+	}
+
+	return loadMenu(*config)
+}
+
 // makeMenu constructs the menu structure with items and sub-items.
 // The handles and paths are set up for each menu item and point to your own handles.
 func makeMenu() *menu.Menu {
 	return &menu.Menu{
-		Title:       fmt.Sprintf("Root Menu (v%s)", version),
-		Description: "This is the root menu",
-		Version:     version,
+		Title:           fmt.Sprintf("Root Menu (v%s)", version),
+		Description:     "This is the root menu",
+		Version:         version,
+		RefreshInterval: time.Minute,
 		Items: []menu.Item{
+			{
+				Title: "Actions",
+				Type:  menu.ItemTypeHeader,
+			},
 			{
 				Title:       "Button (callback)",
 				Description: "Calls back to the server",
@@ -49,6 +147,31 @@ func makeMenu() *menu.Menu {
 				Shortcut:    "cmd+1",
 				Handler:     simple(),
 			},
+			{
+				Title:       "Uptime (command)",
+				Description: "Runs a command and responds with its output",
+				Type:        menu.ItemTypeCallback,
+				OnClick:     "/uptime",
+				Concurrency: menu.ConcurrencyCoalesce,
+				Handler: menu.CommandHandler(menu.Command{
+					Name:    "uptime",
+					Timeout: 2 * time.Second,
+				}),
+			},
+			{
+				Title:       "Copy Time (action)",
+				Description: "Tells the client to copy the time and show a notification",
+				Type:        menu.ItemTypeCallback,
+				OnClick:     "/copy-time",
+				Handler:     menu.ActionFunc(copyTime),
+			},
+			{
+				Title:       "Backup (job)",
+				Description: "Runs in the background and reports its progress",
+				Type:        menu.ItemTypeCallback,
+				OnClick:     "/backup",
+				Job:         backup,
+			},
 			{
 				Title:       "GitHub",
 				Description: "Open GitHub in browser",
@@ -56,6 +179,33 @@ func makeMenu() *menu.Menu {
 				OnClick:     "https://github.com",
 				Shortcut:    "cmd+g",
 			},
+			{
+				Type: menu.ItemTypeSeparator,
+			},
+			{
+				Title:       "Do Not Disturb",
+				Description: "Toggles server-side state",
+				Type:        menu.ItemTypeToggle,
+				OnClick:     "/dnd",
+				OnToggle:    doNotDisturb,
+			},
+			{
+				Title:       "Environment",
+				Description: "Mutually exclusive choices",
+				Type:        menu.ItemTypeRadio,
+				OnClick:     "/env",
+				OnSelect:    environment,
+				Items: []menu.Item{
+					{Title: "Dev", Value: "dev", Checked: true},
+					{Title: "Staging", Value: "staging"},
+					{Title: "Prod", Value: "prod"},
+				},
+			},
+			{
+				Title:       "Live (provider)",
+				Description: "Sub-items computed on each menu fetch",
+				Provider:    menu.ProviderFunc(clock),
+			},
 			{
 				Title:       "Item 2 (submenu)",
 				Description: "This item has subitems",
@@ -82,6 +232,86 @@ func makeMenu() *menu.Menu {
 	}
 }
 
+// registry binds the handler and provider names that can be used in a menu file
+// to the handlers defined in this file.
+func registry() menu.Registry {
+	return menu.Registry{
+		Handlers:  map[string]http.Handler{"simple": simple(), "copyTime": menu.ActionFunc(copyTime)},
+		Toggles:   map[string]menu.ToggleHandler{"doNotDisturb": doNotDisturb},
+		Selects:   map[string]menu.SelectHandler{"environment": environment},
+		Providers: map[string]menu.Provider{"clock": menu.ProviderFunc(clock)},
+		Jobs:      map[string]menu.JobFunc{"backup": backup},
+	}
+}
+
+// loadMenu reads the menu from a file.
+func loadMenu(path string) (*menu.Menu, error) {
+	m, err := menu.Load(path, registry())
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Version == "" {
+		m.Version = version
+	}
+
+	return m, nil
+}
+
+// doNotDisturb is called when the "Do Not Disturb" toggle is clicked.
+func doNotDisturb(_ context.Context, checked bool) error {
+	slog.Info("do not disturb changed", "checked", checked)
+	return nil
+}
+
+// environment is called when an option of the "Environment" radio group is chosen.
+func environment(_ context.Context, value string) error {
+	slog.Info("environment changed", "value", value)
+	return nil
+}
+
+// copyTime is called when the "Copy Time" item is clicked and tells the client what to do next.
+func copyTime(_ *http.Request) (menu.ActionResult, error) {
+	now := time.Now().Format(time.Kitchen)
+
+	res := menu.Notify("Time copied", now)
+	res.Clipboard = now
+
+	return res, nil
+}
+
+// backup is a long-running job started when the "Backup" item is clicked.
+func backup(ctx context.Context, progress menu.Progress) (menu.ActionResult, error) {
+	const steps = 10
+	for i := range steps {
+		progress(float64(i)/steps, fmt.Sprintf("step %d of %d", i+1, steps))
+
+		select {
+		case <-ctx.Done():
+			return menu.ActionResult{}, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	return menu.Notify("Backup complete", fmt.Sprintf("%d steps done", steps)), nil
+}
+
+// clock is a provider that computes its items from live data on each menu fetch.
+func clock(_ context.Context) ([]menu.Item, error) {
+	return []menu.Item{
+		{
+			Title: fmt.Sprintf("Fetched at %s", time.Now().Format(time.Kitchen)),
+			Type:  menu.ItemTypeHeader,
+		},
+		{
+			Title:   "Dynamic callback",
+			Type:    menu.ItemTypeCallback,
+			OnClick: "/live/callback",
+			Handler: simple(),
+		},
+	}, nil
+}
+
 // simple returns a simple handler that responds with request information.
 func simple() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
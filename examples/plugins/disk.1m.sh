@@ -0,0 +1,9 @@
+#!/bin/sh
+# Example xbar/SwiftBar plugin, runs every minute (".1m." in the file name).
+# Lines before "---" are shown as headers, "--" nests lines in a submenu.
+echo "Disk $(df -h / | awk 'NR==2 {print $5}')"
+echo "---"
+echo "Volumes"
+df -h | awk 'NR>1 && $1 ~ /^\// {print "--" $NF " " $5 " used"}'
+echo "Open Disk Utility | bash=/usr/bin/open param1=-a param2='Disk Utility' terminal=false"
+echo "Refresh | refresh=true"
@@ -1,6 +1,7 @@
 import Cocoa
 import Foundation
 import os.log
+import UserNotifications
 
 class AppDelegate: NSObject, NSApplicationDelegate {
     private var statusItem: NSStatusItem!
@@ -9,6 +10,13 @@ class AppDelegate: NSObject, NSApplicationDelegate {
     private let serverPort: Int = 9876
     private let serverPath: String
     private let logger = OSLog(subsystem: "com.mchmarny.momd", category: "app")
+    private var eventStream: EventStream?
+    
+    // Shared secret passed to the server on launch and sent with every request
+    private let authToken = (UUID().uuidString + UUID().uuidString).replacingOccurrences(of: "-", with: "")
+    
+    // Newest ActionResult version this client understands
+    private let supportedActionVersion = 1
     
     override init() {
         // Determine the path to the momd binary
@@ -45,6 +53,7 @@ class AppDelegate: NSObject, NSApplicationDelegate {
         // Wait a moment for server to start, then fetch menu
         DispatchQueue.main.asyncAfter(deadline: .now() + 1.5) {
             self.fetchAndBuildMenu()
+            self.subscribeToEvents()
         }
     }
     
@@ -64,6 +73,11 @@ class AppDelegate: NSObject, NSApplicationDelegate {
         serverProcess?.executableURL = URL(fileURLWithPath: serverPath)
         serverProcess?.arguments = ["-port", "\(serverPort)"]
         
+        // Only this app knows the token, so other local processes cannot call the server
+        var environment = ProcessInfo.processInfo.environment
+        environment["MOMD_TOKEN"] = authToken
+        serverProcess?.environment = environment
+        
         // Capture server output and forward to unified logging
         let outputPipe = Pipe()
         let errorPipe = Pipe()
@@ -99,19 +113,33 @@ class AppDelegate: NSObject, NSApplicationDelegate {
         }
     }
     
+    private func subscribeToEvents() {
+        guard let url = URL(string: "http://127.0.0.1:\(serverPort)/events") else { return }
+        
+        eventStream = EventStream(url: url, token: authToken, logger: logger) { [weak self] event in
+            guard event == "menu-changed" else { return }
+            DispatchQueue.main.async {
+                self?.fetchAndBuildMenu()
+            }
+        }
+        eventStream?.connect()
+    }
+    
     private func stopServer() {
+        eventStream?.disconnect()
+        eventStream = nil
         serverProcess?.terminate()
         serverProcess = nil
         os_log("Server stopped", log: logger, type: .info)
     }
     
     private func fetchAndBuildMenu() {
-        guard let url = URL(string: "http://localhost:\(serverPort)/") else {
+        guard let url = URL(string: "http://127.0.0.1:\(serverPort)/") else {
             showError("Invalid server URL")
             return
         }
         
-        let task = URLSession.shared.dataTask(with: url) { [weak self] data, response, error in
+        let task = URLSession.shared.dataTask(with: authorizedRequest(url)) { [weak self] data, response, error in
             guard let self = self else { return }
             
             if let error = error {
@@ -172,6 +200,23 @@ class AppDelegate: NSObject, NSApplicationDelegate {
     }
     
     private func addMenuItem(_ item: MenuItem, to menu: NSMenu) {
+        switch item.type {
+        case "separator":
+            menu.addItem(NSMenuItem.separator())
+            return
+        case "header":
+            if #available(macOS 14.0, *) {
+                menu.addItem(NSMenuItem.sectionHeader(title: item.title))
+            } else {
+                let header = NSMenuItem(title: item.title, action: nil, keyEquivalent: "")
+                header.isEnabled = false
+                menu.addItem(header)
+            }
+            return
+        default:
+            break
+        }
+        
         let menuItem = NSMenuItem(title: item.title, action: nil, keyEquivalent: "")
         
         // Set tooltip from description if available
@@ -179,6 +224,28 @@ class AppDelegate: NSObject, NSApplicationDelegate {
             menuItem.toolTip = description
         }
         
+        // Disabled items are shown greyed out, without an action or submenu
+        if item.disabled ?? false {
+            menuItem.isEnabled = false
+            menu.addItem(menuItem)
+            return
+        }
+        
+        // Radio groups are submenus of mutually exclusive options
+        if item.type == "radio", let onClick = item.onClick, let options = item.items {
+            let submenu = NSMenu(title: item.title)
+            for option in options {
+                let optionItem = NSMenuItem(title: option.title, action: #selector(handleRadioOption(_:)), keyEquivalent: "")
+                optionItem.target = self
+                optionItem.state = (option.checked ?? false) ? .on : .off
+                optionItem.representedObject = RadioOption(onClick: onClick, method: item.method ?? "POST", value: option.value ?? option.title)
+                submenu.addItem(optionItem)
+            }
+            menuItem.submenu = submenu
+            menu.addItem(menuItem)
+            return
+        }
+        
         // If item has children, create submenu
         if let children = item.items, !children.isEmpty {
             let submenu = NSMenu(title: item.title)
@@ -190,7 +257,11 @@ class AppDelegate: NSObject, NSApplicationDelegate {
             // If item has an onClick action, make it actionable
             menuItem.target = self
             menuItem.action = #selector(handleMenuItemAction(_:))
-            menuItem.representedObject = MenuItemAction(type: item.type, onClick: onClick)
+            menuItem.representedObject = MenuItemAction(type: item.type, onClick: onClick, method: item.method ?? "POST")
+            
+            if item.type == "toggle" {
+                menuItem.state = (item.checked ?? false) ? .on : .off
+            }
             
             // Parse and set keyboard shortcut AFTER action is set
             if let shortcut = item.shortcut, !shortcut.isEmpty {
@@ -224,35 +295,84 @@ class AppDelegate: NSObject, NSApplicationDelegate {
                 modifiers.insert(.shift)
             default:
                 // This is the key itself
-                key = part
+                key = keyEquivalent(for: part)
             }
         }
         
         return (key, modifiers)
     }
     
+    // Maps canonical named keys served by the Go server to NSMenuItem key equivalents
+    private func keyEquivalent(for key: String) -> String {
+        switch key {
+        case "space":
+            return " "
+        case "tab":
+            return "\t"
+        case "return":
+            return "\r"
+        case "escape":
+            return "\u{1b}"
+        case "delete":
+            return "\u{8}"
+        case "plus":
+            return "+"
+        case "up":
+            return String(Character(UnicodeScalar(NSUpArrowFunctionKey)!))
+        case "down":
+            return String(Character(UnicodeScalar(NSDownArrowFunctionKey)!))
+        case "left":
+            return String(Character(UnicodeScalar(NSLeftArrowFunctionKey)!))
+        case "right":
+            return String(Character(UnicodeScalar(NSRightArrowFunctionKey)!))
+        case "home":
+            return String(Character(UnicodeScalar(NSHomeFunctionKey)!))
+        case "end":
+            return String(Character(UnicodeScalar(NSEndFunctionKey)!))
+        case "pageup":
+            return String(Character(UnicodeScalar(NSPageUpFunctionKey)!))
+        case "pagedown":
+            return String(Character(UnicodeScalar(NSPageDownFunctionKey)!))
+        default:
+            if key.count > 1, key.hasPrefix("f"), let n = Int(key.dropFirst()), (1...20).contains(n) {
+                return String(Character(UnicodeScalar(NSF1FunctionKey + n - 1)!))
+            }
+            return key
+        }
+    }
+    
     @objc private func handleMenuItemAction(_ sender: NSMenuItem) {
         guard let action = sender.representedObject as? MenuItemAction else { return }
         
         switch action.type {
         case "callback":
-            handleCallback(path: action.onClick)
+            handleCallback(path: action.onClick, method: action.method)
         case "link":
             handleLink(path: action.onClick)
+        case "toggle":
+            handleToggle(sender, path: action.onClick, method: action.method)
         default:
             showError("Unknown menu item type: \(action.type)")
         }
     }
     
-    private func handleCallback(path: String) {
-        guard let url = URL(string: "http://localhost:\(serverPort)\(path)") else {
+    // Builds an authenticated request to the server, with the method declared by the item for callbacks
+    private func authorizedRequest(_ url: URL, method: String = "GET") -> URLRequest {
+        var request = URLRequest(url: url)
+        request.httpMethod = method
+        request.setValue("Bearer \(authToken)", forHTTPHeaderField: "Authorization")
+        return request
+    }
+    
+    private func handleCallback(path: String, method: String) {
+        guard let url = URL(string: "http://127.0.0.1:\(serverPort)\(path)") else {
             showError("Invalid URL for path: \(path)")
             return
         }
         
-        os_log("Invoking callback: %{public}@", log: logger, type: .info, path)
+        os_log("Invoking callback: %{public}@ %{public}@", log: logger, type: .info, method, path)
         
-        let task = URLSession.shared.dataTask(with: url) { [weak self] data, response, error in
+        let task = URLSession.shared.dataTask(with: authorizedRequest(url, method: method)) { [weak self] data, response, error in
             guard let self = self else { return }
             if let error = error {
                 os_log("Failed to invoke callback: %{public}@", log: self.logger, type: .error, error.localizedDescription)
@@ -265,6 +385,173 @@ class AppDelegate: NSObject, NSApplicationDelegate {
             if let data = data, let responseString = String(data: data, encoding: .utf8) {
                 os_log("Response from %{public}@: %{public}@", log: self.logger, type: .debug, path, responseString)
             }
+            
+            // Clicks received while a previous one is still running may be dropped, queued or coalesced
+            if let http = response as? HTTPURLResponse, let decision = http.value(forHTTPHeaderField: "Momd-Concurrency"), decision != "ran" {
+                os_log("Click on %{public}@ %{public}@", log: self.logger, type: .info, path, decision)
+            }
+            
+            // Responses that are not an ActionResult are opaque and only logged
+            guard let data = data,
+                  let result = try? JSONDecoder().decode(ActionResult.self, from: data),
+                  let version = result.version else {
+                return
+            }
+            
+            guard version <= self.supportedActionVersion else {
+                os_log("Ignoring action result version %d from %{public}@", log: self.logger, type: .error, version, path)
+                return
+            }
+            
+            DispatchQueue.main.async {
+                self.perform(result)
+            }
+        }
+        task.resume()
+    }
+    
+    // Performs what the server asked for after a callback, in the documented order
+    private func perform(_ result: ActionResult) {
+        if let job = result.job {
+            os_log("Job %{public}@ started by %{public}@", log: logger, type: .info, job.id, job.item)
+            pollJob(id: job.id)
+        }
+        
+        if let notification = result.notification {
+            showNotification(title: notification.title, body: notification.body ?? "")
+        }
+        
+        if let openURL = result.openURL {
+            handleLink(path: openURL)
+        }
+        
+        if let text = result.clipboard {
+            NSPasteboard.general.clearContents()
+            NSPasteboard.general.setString(text, forType: .string)
+        }
+        
+        if result.refresh == true {
+            fetchAndBuildMenu()
+        }
+        
+        if let message = result.error {
+            showError(message)
+        }
+    }
+    
+    // Polls a background job until it finishes, then performs its result
+    private func pollJob(id: String) {
+        guard let url = URL(string: "http://127.0.0.1:\(serverPort)/jobs/\(id)") else { return }
+        
+        DispatchQueue.main.asyncAfter(deadline: .now() + 1) { [weak self] in
+            guard let self = self else { return }
+            let task = URLSession.shared.dataTask(with: self.authorizedRequest(url)) { [weak self] data, response, error in
+                guard let self = self else { return }
+                
+                guard let data = data, let job = try? JSONDecoder().decode(JobState.self, from: data) else {
+                    os_log("Failed to poll job %{public}@: %{public}@", log: self.logger, type: .error, id, error?.localizedDescription ?? "invalid response")
+                    return
+                }
+                
+                if job.status == "queued" || job.status == "running" {
+                    os_log("Job %{public}@ at %.0f%%: %{public}@", log: self.logger, type: .debug, id, job.progress * 100, job.message ?? "")
+                    self.pollJob(id: id)
+                    return
+                }
+                
+                os_log("Job %{public}@ %{public}@", log: self.logger, type: .info, id, job.status)
+                if let result = job.result {
+                    DispatchQueue.main.async {
+                        self.perform(result)
+                    }
+                }
+            }
+            task.resume()
+        }
+    }
+    
+    private func showNotification(title: String, body: String) {
+        let center = UNUserNotificationCenter.current()
+        center.requestAuthorization(options: [.alert, .sound]) { [weak self] granted, error in
+            guard let self = self else { return }
+            guard granted else {
+                os_log("Notifications not authorized: %{public}@", log: self.logger, type: .error, title)
+                return
+            }
+            
+            let content = UNMutableNotificationContent()
+            content.title = title
+            content.body = body
+            
+            let request = UNNotificationRequest(identifier: UUID().uuidString, content: content, trigger: nil)
+            center.add(request)
+        }
+    }
+    
+    private func handleToggle(_ sender: NSMenuItem, path: String, method: String) {
+        let requested = sender.state != .on
+        guard let url = URL(string: "http://127.0.0.1:\(serverPort)\(path)?checked=\(requested)") else {
+            showError("Invalid URL for path: \(path)")
+            return
+        }
+        
+        os_log("Toggling %{public}@ to %{public}@", log: logger, type: .info, path, requested ? "on" : "off")
+        
+        let task = URLSession.shared.dataTask(with: authorizedRequest(url, method: method)) { [weak self] data, response, error in
+            guard let self = self else { return }
+            if let error = error {
+                os_log("Failed to toggle: %{public}@", log: self.logger, type: .error, error.localizedDescription)
+                DispatchQueue.main.async {
+                    self.showError("Failed to toggle: \(error.localizedDescription)")
+                }
+                return
+            }
+            
+            guard let data = data, let result = try? JSONDecoder().decode(ToggleResult.self, from: data) else {
+                os_log("Toggle %{public}@ rejected by server", log: self.logger, type: .error, path)
+                return
+            }
+            
+            DispatchQueue.main.async {
+                sender.state = result.checked ? .on : .off
+            }
+        }
+        task.resume()
+    }
+    
+    @objc private func handleRadioOption(_ sender: NSMenuItem) {
+        guard let option = sender.representedObject as? RadioOption else { return }
+        var components = URLComponents(string: "http://127.0.0.1:\(serverPort)\(option.onClick)")
+        components?.queryItems = [URLQueryItem(name: "value", value: option.value)]
+        guard let url = components?.url else {
+            showError("Invalid URL for path: \(option.onClick)")
+            return
+        }
+        
+        os_log("Selecting %{public}@ for %{public}@", log: logger, type: .info, option.value, option.onClick)
+        
+        let task = URLSession.shared.dataTask(with: authorizedRequest(url, method: option.method)) { [weak self] data, response, error in
+            guard let self = self else { return }
+            if let error = error {
+                os_log("Failed to select option: %{public}@", log: self.logger, type: .error, error.localizedDescription)
+                DispatchQueue.main.async {
+                    self.showError("Failed to select option: \(error.localizedDescription)")
+                }
+                return
+            }
+            
+            guard let data = data, let result = try? JSONDecoder().decode(RadioResult.self, from: data) else {
+                os_log("Selection for %{public}@ rejected by server", log: self.logger, type: .error, option.onClick)
+                return
+            }
+            
+            DispatchQueue.main.async {
+                for sibling in sender.menu?.items ?? [] {
+                    if let other = sibling.representedObject as? RadioOption {
+                        sibling.state = other.value == result.selected ? .on : .off
+                    }
+                }
+            }
         }
         task.resume()
     }
@@ -306,13 +593,133 @@ struct MenuData: Codable {
 struct MenuItem: Codable {
     let type: String
     let onClick: String?
+    let method: String?
     let title: String
     let description: String?
     let shortcut: String?
+    let disabled: Bool?
+    let checked: Bool?
+    let value: String?
     let items: [MenuItem]?
 }
 
+struct RadioOption {
+    let onClick: String
+    let method: String
+    let value: String
+}
+
+struct RadioResult: Codable {
+    let selected: String
+}
+
+struct ToggleResult: Codable {
+    let checked: Bool
+}
+
+struct ActionResult: Codable {
+    let version: Int?
+    let notification: ActionNotification?
+    let openURL: String?
+    let clipboard: String?
+    let refresh: Bool?
+    let error: String?
+    let job: JobRef?
+}
+
+struct JobRef: Codable {
+    let id: String
+    let item: String
+}
+
+struct JobState: Codable {
+    let id: String
+    let status: String
+    let progress: Double
+    let message: String?
+    let result: ActionResult?
+}
+
+struct ActionNotification: Codable {
+    let title: String
+    let body: String?
+}
+
 struct MenuItemAction {
     let type: String
     let onClick: String
+    let method: String
+}
+
+// MARK: - Event Stream
+
+// Listens to the Server-Sent Events stream of menu changes and reconnects when it drops
+class EventStream: NSObject, URLSessionDataDelegate {
+    private let url: URL
+    private let token: String
+    private let logger: OSLog
+    private let onEvent: (String) -> Void
+    private var session: URLSession?
+    private var buffer = ""
+    private var eventName = ""
+    private var retryDelay: TimeInterval = 3
+    private var stopped = false
+    
+    init(url: URL, token: String, logger: OSLog, onEvent: @escaping (String) -> Void) {
+        self.url = url
+        self.token = token
+        self.logger = logger
+        self.onEvent = onEvent
+        super.init()
+    }
+    
+    func connect() {
+        guard !stopped else { return }
+        let config = URLSessionConfiguration.default
+        config.timeoutIntervalForRequest = .infinity
+        session = URLSession(configuration: config, delegate: self, delegateQueue: nil)
+        var request = URLRequest(url: url)
+        request.setValue("text/event-stream", forHTTPHeaderField: "Accept")
+        request.setValue("Bearer \(token)", forHTTPHeaderField: "Authorization")
+        session?.dataTask(with: request).resume()
+        os_log("Connected to event stream", log: logger, type: .info)
+    }
+    
+    func disconnect() {
+        stopped = true
+        session?.invalidateAndCancel()
+        session = nil
+    }
+    
+    func urlSession(_ session: URLSession, dataTask: URLSessionDataTask, didReceive data: Data) {
+        guard let chunk = String(data: data, encoding: .utf8) else { return }
+        buffer += chunk
+        while let range = buffer.range(of: "\n") {
+            let line = String(buffer[..<range.lowerBound])
+            buffer.removeSubrange(..<range.upperBound)
+            handleLine(line)
+        }
+    }
+    
+    func urlSession(_ session: URLSession, task: URLSessionTask, didCompleteWithError error: Error?) {
+        guard !stopped else { return }
+        os_log("Event stream closed, reconnecting in %.0fs", log: logger, type: .info, retryDelay)
+        DispatchQueue.main.asyncAfter(deadline: .now() + retryDelay) { [weak self] in
+            self?.connect()
+        }
+    }
+    
+    private func handleLine(_ line: String) {
+        if line.isEmpty {
+            // A blank line dispatches the event
+            if !eventName.isEmpty {
+                onEvent(eventName)
+            }
+            eventName = ""
+        } else if line.hasPrefix("event:") {
+            eventName = line.dropFirst("event:".count).trimmingCharacters(in: .whitespaces)
+        } else if line.hasPrefix("retry:"), let ms = Double(line.dropFirst("retry:".count).trimmingCharacters(in: .whitespaces)) {
+            retryDelay = ms / 1000
+        }
+    }
 }
@@ -0,0 +1,77 @@
+package menu
+
+import (
+	"net/http"
+)
+
+// ActionResultVersion is the version of the ActionResult format written by WriteAction.
+// Clients must ignore fields they do not know and results with a version newer than they support.
+const ActionResultVersion = 1
+
+// ActionResult tells the client what to do after a callback item is clicked.
+// Every field is optional; clients perform each action that is set, in field order,
+// and treat any other callback response as opaque (logged only).
+type ActionResult struct {
+	// Version is the version of the result format (see ActionResultVersion). Set by WriteAction.
+	Version int `json:"version"`
+
+	// Notification is shown to the user as a system notification.
+	Notification *Notification `json:"notification,omitempty"`
+
+	// OpenURL is opened with the default handler (e.g. a browser for https URLs).
+	OpenURL string `json:"openURL,omitempty"`
+
+	// Clipboard is copied to the clipboard as plain text.
+	Clipboard string `json:"clipboard,omitempty"`
+
+	// Refresh asks the client to fetch the menu again.
+	Refresh bool `json:"refresh,omitempty"`
+
+	// Error is shown to the user as an error message.
+	Error string `json:"error,omitempty"`
+
+	// Job is the job started by the click, when the item runs as a job.
+	// Clients poll it at JobsPath/{id} and perform its Result once it has finished.
+	Job *Job `json:"job,omitempty"`
+}
+
+// Notification is a system notification shown by the client.
+type Notification struct {
+	// Title of the notification.
+	Title string `json:"title"`
+
+	// Body of the notification.
+	Body string `json:"body,omitempty"`
+}
+
+// Notify returns a result that shows a notification with the given title and body.
+func Notify(title, body string) ActionResult {
+	return ActionResult{Notification: &Notification{Title: title, Body: body}}
+}
+
+// WriteAction writes the result as the JSON response of a callback with the given status code,
+// setting its version.
+func WriteAction(w http.ResponseWriter, status int, res ActionResult) {
+	res.Version = ActionResultVersion
+	writeJSON(w, status, res)
+}
+
+// WriteActionError writes a result that shows the error message to the user, with the given status code.
+func WriteActionError(w http.ResponseWriter, status int, err error) {
+	WriteAction(w, status, ActionResult{Error: err.Error()})
+}
+
+// ActionFunc is a callback handler that returns what the client should do after the click.
+// A returned error is written as an ActionResult with the error message and status 500.
+type ActionFunc func(r *http.Request) (ActionResult, error)
+
+// ServeHTTP implements http.Handler.
+func (f ActionFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	res, err := f(r)
+	if err != nil {
+		WriteActionError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	WriteAction(w, http.StatusOK, res)
+}
@@ -0,0 +1,63 @@
+package menu
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestActionFunc(t *testing.T) {
+	call := func(t *testing.T, h http.Handler) (int, map[string]any) {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/action", nil))
+
+		var body map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		return rec.Code, body
+	}
+
+	t.Run("result is written with its version", func(t *testing.T) {
+		code, body := call(t, ActionFunc(func(*http.Request) (ActionResult, error) {
+			res := Notify("Deployed", "v1.2.3 is live")
+			res.OpenURL = "https://example.com/deploys/42"
+			res.Refresh = true
+			return res, nil
+		}))
+
+		if code != http.StatusOK {
+			t.Errorf("expected 200, got %d", code)
+		}
+
+		want := map[string]any{
+			"version":      float64(ActionResultVersion),
+			"notification": map[string]any{"title": "Deployed", "body": "v1.2.3 is live"},
+			"openURL":      "https://example.com/deploys/42",
+			"refresh":      true,
+		}
+		got, _ := json.Marshal(body)
+		exp, _ := json.Marshal(want)
+		if string(got) != string(exp) {
+			t.Errorf("expected %s, got %s", exp, got)
+		}
+	})
+
+	t.Run("error is written as a result", func(t *testing.T) {
+		code, body := call(t, ActionFunc(func(*http.Request) (ActionResult, error) {
+			return ActionResult{}, errors.New("deploy failed")
+		}))
+
+		if code != http.StatusInternalServerError {
+			t.Errorf("expected 500, got %d", code)
+		}
+		if body["error"] != "deploy failed" || body["version"] != float64(ActionResultVersion) {
+			t.Errorf("unexpected body: %v", body)
+		}
+	})
+}
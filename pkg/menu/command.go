@@ -0,0 +1,195 @@
+package menu
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCommandTimeout is the maximum duration a command may run when it does not set its own Timeout.
+	// It is kept below the server DefaultWriteTimeout so that the result can still be sent.
+	DefaultCommandTimeout = 8 * time.Second
+
+	// MaxCommandOutput is the maximum number of bytes captured from each of stdout and stderr.
+	// Output beyond this limit is discarded.
+	MaxCommandOutput = 1 << 20 // 1 MB
+
+	// DefaultCommandJobTimeout is the maximum duration a command run as a job may run
+	// when it does not set its own Timeout.
+	DefaultCommandJobTimeout = time.Hour
+
+	// commandWaitDelay is how long to wait for the output pipes to close after the
+	// command exits or is killed, in case it left processes holding them open.
+	commandWaitDelay = time.Second
+)
+
+// Command describes an external command run by a callback item.
+type Command struct {
+	// Name is the program to run, resolved through PATH when it has no path separator.
+	Name string
+
+	// Args are the arguments passed to the program.
+	Args []string
+
+	// Dir is the working directory. If not specified, the server working directory is used.
+	Dir string
+
+	// Env are additional "KEY=VALUE" variables, added to the server environment.
+	Env []string
+
+	// Timeout is the maximum duration the command may run, after which its whole process
+	// group is killed. If not specified, DefaultCommandTimeout is used.
+	Timeout time.Duration
+}
+
+// CommandResult is the outcome of running a Command, served as JSON by CommandHandler.
+type CommandResult struct {
+	// Command is the command line that was run.
+	Command string `json:"command"`
+
+	// ExitCode is the exit code of the process, or -1 if it did not exit normally.
+	ExitCode int `json:"exitCode"`
+
+	// Stdout is the captured standard output, up to MaxCommandOutput bytes.
+	Stdout string `json:"stdout"`
+
+	// Stderr is the captured standard error, up to MaxCommandOutput bytes.
+	Stderr string `json:"stderr"`
+
+	// DurationMs is how long the command ran, in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+
+	// TimedOut is true when the command was killed because it exceeded its timeout.
+	TimedOut bool `json:"timedOut,omitempty"`
+
+	// Error describes why the command failed to start or did not complete successfully.
+	Error string `json:"error,omitempty"`
+}
+
+// Run runs the command, waits for it to complete and returns its result.
+// On timeout or context cancellation the whole process group is killed.
+func (c Command) Run(ctx context.Context) CommandResult {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = append(os.Environ(), c.Env...)
+	cmd.WaitDelay = commandWaitDelay
+	killProcessGroup(cmd)
+
+	var stdout, stderr limitedBuffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	res := CommandResult{Command: strings.Join(append([]string{c.Name}, c.Args...), " ")}
+
+	start := time.Now()
+	err := cmd.Run()
+	res.DurationMs = time.Since(start).Milliseconds()
+	res.Stdout = stdout.String()
+	res.Stderr = stderr.String()
+	res.ExitCode = -1
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if err != nil {
+		res.Error = err.Error()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			res.TimedOut = true
+			res.Error = "command timed out after " + timeout.String()
+		}
+	}
+
+	return res
+}
+
+// CommandHandler returns an HTTP handler that runs the command on every request and responds
+// with its CommandResult as JSON: 200 when it exits with 0, 504 when it times out, 500 otherwise.
+// The command is killed if the request is canceled.
+func CommandHandler(c Command) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := c.Run(r.Context())
+
+		status := http.StatusOK
+		switch {
+		case res.TimedOut:
+			status = http.StatusGatewayTimeout
+		case res.Error != "":
+			status = http.StatusInternalServerError
+		}
+
+		slog.Info("command completed",
+			"command", res.Command,
+			"exit_code", res.ExitCode,
+			"duration_ms", res.DurationMs,
+			"timed_out", res.TimedOut,
+			"error", res.Error,
+		)
+
+		writeJSON(w, status, res)
+	})
+}
+
+// CommandJob returns a job that runs the command in the background, for commands that take
+// longer than a request may (e.g. deploys, backups). If the command does not set its own Timeout,
+// DefaultCommandJobTimeout is used. When it succeeds the client is notified with the last line
+// of its output; otherwise the job fails with its error and standard error.
+func CommandJob(c Command) JobFunc {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultCommandJobTimeout
+	}
+
+	return func(ctx context.Context, _ Progress) (ActionResult, error) {
+		res := c.Run(ctx)
+
+		slog.Info("command job completed",
+			"command", res.Command,
+			"exit_code", res.ExitCode,
+			"duration_ms", res.DurationMs,
+			"timed_out", res.TimedOut,
+			"error", res.Error,
+		)
+
+		if res.Error != "" {
+			if stderr := strings.TrimSpace(res.Stderr); stderr != "" {
+				return ActionResult{}, fmt.Errorf("%s: %s", res.Error, stderr)
+			}
+			return ActionResult{}, errors.New(res.Error)
+		}
+
+		lines := strings.Split(strings.TrimSpace(res.Stdout), "\n")
+
+		return Notify(filepath.Base(c.Name)+" finished", lines[len(lines)-1]), nil
+	}
+}
+
+// limitedBuffer is an io.Writer that keeps the first MaxCommandOutput bytes written to it
+// and silently discards the rest, so that a chatty command cannot exhaust memory.
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+// Write implements io.Writer and always reports the full length as written.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := MaxCommandOutput - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+
+	return len(p), nil
+}
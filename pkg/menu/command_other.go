@@ -0,0 +1,9 @@
+//go:build !unix
+
+package menu
+
+import "os/exec"
+
+// killProcessGroup is a no-op on platforms without process groups,
+// where cancellation only kills the command process itself.
+func killProcessGroup(_ *exec.Cmd) {}
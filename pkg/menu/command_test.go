@@ -0,0 +1,75 @@
+//go:build unix
+
+package menu
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommand(t *testing.T) {
+	t.Run("captures output, exit code and environment", func(t *testing.T) {
+		res := Command{
+			Name: "sh",
+			Args: []string{"-c", `echo "$GREETING from $(pwd)"; echo oops >&2; exit 3`},
+			Dir:  "/",
+			Env:  []string{"GREETING=hello"},
+		}.Run(context.Background())
+
+		if res.Stdout != "hello from /\n" {
+			t.Errorf("unexpected stdout %q", res.Stdout)
+		}
+		if res.Stderr != "oops\n" {
+			t.Errorf("unexpected stderr %q", res.Stderr)
+		}
+		if res.ExitCode != 3 || res.Error == "" {
+			t.Errorf("expected exit code 3 with error, got %d %q", res.ExitCode, res.Error)
+		}
+	})
+
+	t.Run("kills the process group on timeout", func(t *testing.T) {
+		start := time.Now()
+		res := Command{
+			Name:    "sh",
+			Args:    []string{"-c", "sleep 10 & sleep 10; wait"},
+			Timeout: 100 * time.Millisecond,
+		}.Run(context.Background())
+
+		if !res.TimedOut {
+			t.Errorf("expected timeout, got %+v", res)
+		}
+		if d := time.Since(start); d > 3*time.Second {
+			t.Errorf("expected command to be killed quickly, took %v", d)
+		}
+	})
+
+	t.Run("handler responds with the result", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		CommandHandler(Command{Name: "echo", Args: []string{"hi"}}).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/run", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var res CommandResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		if res.Stdout != "hi\n" || res.ExitCode != 0 || res.Command != "echo hi" {
+			t.Errorf("unexpected result %+v", res)
+		}
+
+		rec = httptest.NewRecorder()
+		CommandHandler(Command{Name: "does-not-exist"}).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/run", nil))
+		if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "executable file not found") {
+			t.Errorf("expected start failure, got %d %s", rec.Code, rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package menu
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"sync"
+)
+
+const (
+	// ConcurrencyAllow runs every click, even while previous clicks are still running. This is the default.
+	ConcurrencyAllow Concurrency = "allow"
+	// ConcurrencyDrop ignores clicks while a previous click is still running; they get 409 Conflict.
+	ConcurrencyDrop Concurrency = "drop"
+	// ConcurrencyQueue runs clicks one at a time, in order; later clicks wait for earlier ones to finish.
+	ConcurrencyQueue Concurrency = "queue"
+	// ConcurrencyCoalesce runs a single click at a time and gives every click received
+	// while it is running the same response.
+	ConcurrencyCoalesce Concurrency = "coalesce"
+
+	// ConcurrencyHeader is the response header reporting what the concurrency policy
+	// of a callback item did with the click (see Concurrency).
+	ConcurrencyHeader = "Momd-Concurrency"
+)
+
+// Reported in the ConcurrencyHeader of callback responses.
+const (
+	// decisionRan means the click ran right away.
+	decisionRan = "ran"
+	// decisionQueued means the click ran after waiting for previous clicks to finish.
+	decisionQueued = "queued"
+	// decisionDropped means the click was ignored because a previous click was still running.
+	decisionDropped = "dropped"
+	// decisionCoalesced means the click got the response of a click that was already running.
+	decisionCoalesced = "coalesced"
+	// decisionAbandoned means the client gave up while the click waited for previous clicks to finish.
+	decisionAbandoned = "abandoned"
+)
+
+// Concurrency is the policy applied to clicks on a callback item received while
+// a previous click is still running (e.g. a double-click on a slow item).
+type Concurrency string
+
+// valid reports whether c is a known policy. The empty value is ConcurrencyAllow.
+func (c Concurrency) valid() bool {
+	switch c {
+	case "", ConcurrencyAllow, ConcurrencyDrop, ConcurrencyQueue, ConcurrencyCoalesce:
+		return true
+	default:
+		return false
+	}
+}
+
+// lanes holds the in-flight clicks of callback items, keyed by their onClick path,
+// so that policies keep applying when routes are rebuilt. The zero value is ready to use.
+type lanes struct {
+	mu    sync.Mutex
+	paths map[string]*lane
+}
+
+// lane tracks the clicks of a single callback item.
+type lane struct {
+	sem chan struct{} // held by the running click or job (drop and queue)
+
+	mu   sync.Mutex
+	call *call  // running click that others coalesce into
+	job  string // ID of the last job started, that others coalesce into while it runs
+}
+
+// call is a running click whose response is shared by coalesced clicks.
+type call struct {
+	done chan struct{}
+	res  *recorder
+}
+
+// lane returns the lane of the callback item at path, creating it if needed.
+func (l *lanes) lane(path string) *lane {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.paths == nil {
+		l.paths = map[string]*lane{}
+	}
+
+	ln, ok := l.paths[path]
+	if !ok {
+		ln = &lane{sem: make(chan struct{}, 1)}
+		l.paths[path] = ln
+	}
+
+	return ln
+}
+
+// guard wraps the handler of a callback item with its concurrency policy.
+// Items that allow concurrent clicks are returned as-is.
+func (m *Menu) guard(item *Item, h http.Handler) http.Handler {
+	path, policy := item.OnClick, item.Concurrency
+
+	switch policy {
+	case ConcurrencyDrop:
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ln := m.lanes.lane(path)
+
+			if !ln.tryAcquire() {
+				writeDropped(w)
+				return
+			}
+			defer ln.release()
+
+			w.Header().Set(ConcurrencyHeader, decisionRan)
+			h.ServeHTTP(w, r)
+		})
+	case ConcurrencyQueue:
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ln := m.lanes.lane(path)
+
+			decision, ok := ln.acquire(r.Context())
+			if !ok {
+				writeAbandoned(w)
+				return
+			}
+			defer ln.release()
+
+			w.Header().Set(ConcurrencyHeader, decision)
+			h.ServeHTTP(w, r)
+		})
+	case ConcurrencyCoalesce:
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.lanes.lane(path).coalesce(w, r, h)
+		})
+	default:
+		return h
+	}
+}
+
+// tryAcquire takes the lane if no click is running in it.
+func (ln *lane) tryAcquire() bool {
+	select {
+	case ln.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// acquire takes the lane, waiting for the running click to release it if needed.
+// Returns the decision reported to the client, or false if the client gave up waiting.
+func (ln *lane) acquire(ctx context.Context) (string, bool) {
+	if ln.tryAcquire() {
+		return decisionRan, true
+	}
+
+	if !ln.wait(ctx) {
+		return "", false
+	}
+
+	return decisionQueued, true
+}
+
+// wait waits for the lane to be free and takes it. Returns false if ctx is done first.
+func (ln *lane) wait(ctx context.Context) bool {
+	select {
+	case ln.sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees the lane for the next click.
+func (ln *lane) release() {
+	<-ln.sem
+}
+
+// writeDropped responds to a click ignored because a previous click is still running.
+func writeDropped(w http.ResponseWriter) {
+	w.Header().Set(ConcurrencyHeader, decisionDropped)
+	WriteAction(w, http.StatusConflict, ActionResult{})
+}
+
+// writeAbandoned responds to a click whose client gave up waiting for previous clicks to finish,
+// so that it is not recorded as a success. The client is likely gone and never reads it.
+func writeAbandoned(w http.ResponseWriter) {
+	w.Header().Set(ConcurrencyHeader, decisionAbandoned)
+	WriteActionError(w, http.StatusServiceUnavailable, errors.New("gave up waiting for previous clicks to finish"))
+}
+
+// coalesce runs the handler unless a click is already running, in which case it waits
+// for that click and writes the same response.
+func (ln *lane) coalesce(w http.ResponseWriter, r *http.Request, h http.Handler) {
+	ln.mu.Lock()
+	if c := ln.call; c != nil {
+		ln.mu.Unlock()
+
+		select {
+		case <-c.done:
+			c.res.replay(w, decisionCoalesced)
+		case <-r.Context().Done():
+		}
+		return
+	}
+
+	c := &call{done: make(chan struct{}), res: &recorder{header: http.Header{}}}
+	ln.call = c
+	ln.mu.Unlock()
+
+	defer func() {
+		ln.mu.Lock()
+		ln.call = nil
+		ln.mu.Unlock()
+		close(c.done)
+	}()
+
+	// Coalesced clicks depend on this response, so it is not cut short when this client goes away
+	h.ServeHTTP(c.res, r.WithContext(context.WithoutCancel(r.Context())))
+	c.res.replay(w, decisionRan)
+}
+
+// recorder is an http.ResponseWriter that keeps the response so it can be written to several clients.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header implements http.ResponseWriter.
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (rec *recorder) Write(b []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(b)
+}
+
+// replay writes the recorded response with the concurrency decision.
+func (rec *recorder) replay(w http.ResponseWriter, decision string) {
+	maps.Copy(w.Header(), rec.header)
+	w.Header().Set(ConcurrencyHeader, decision)
+
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write(rec.body.Bytes()) // client may have gone away
+}
@@ -0,0 +1,152 @@
+package menu
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrency(t *testing.T) {
+	// slow returns a handler that signals when it starts and blocks until released.
+	slow := func(runs *atomic.Int32, started chan<- struct{}, release <-chan struct{}) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			n := runs.Add(1)
+			started <- struct{}{}
+			<-release
+			writeJSON(w, http.StatusOK, map[string]int32{"run": n})
+		})
+	}
+
+	// click sends a click in the background and returns its response once done.
+	click := func(h http.Handler, path string) <-chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+			done <- rec
+		}()
+		return done
+	}
+
+	setup := func(policy Concurrency) (http.Handler, *atomic.Int32, chan struct{}, chan struct{}) {
+		var runs atomic.Int32
+		started, release := make(chan struct{}, 2), make(chan struct{})
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{{Title: "Slow", Type: ItemTypeCallback, OnClick: "/slow", Concurrency: policy, Handler: slow(&runs, started, release)}},
+		}
+		return serve(m), &runs, started, release
+	}
+
+	t.Run("drop ignores clicks while running", func(t *testing.T) {
+		mux, runs, started, release := setup(ConcurrencyDrop)
+
+		first := click(mux, "/slow")
+		<-started
+
+		second := <-click(mux, "/slow")
+		if second.Code != http.StatusConflict || second.Header().Get(ConcurrencyHeader) != "dropped" {
+			t.Errorf("expected dropped click, got %d %q", second.Code, second.Header().Get(ConcurrencyHeader))
+		}
+
+		close(release)
+		if rec := <-first; rec.Code != http.StatusOK || rec.Header().Get(ConcurrencyHeader) != "ran" {
+			t.Errorf("expected first click to run, got %d %q", rec.Code, rec.Header().Get(ConcurrencyHeader))
+		}
+		if runs.Load() != 1 {
+			t.Errorf("expected 1 run, got %d", runs.Load())
+		}
+	})
+
+	t.Run("queue runs clicks one at a time", func(t *testing.T) {
+		mux, runs, started, release := setup(ConcurrencyQueue)
+
+		first := click(mux, "/slow")
+		<-started
+		second := click(mux, "/slow")
+
+		select {
+		case <-started:
+			t.Fatal("expected second click to wait for the first")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		release <- struct{}{}
+		<-first
+		<-started
+		close(release)
+
+		if rec := <-second; rec.Header().Get(ConcurrencyHeader) != "queued" || !strings.Contains(rec.Body.String(), `"run":2`) {
+			t.Errorf("expected queued second run, got %q %s", rec.Header().Get(ConcurrencyHeader), rec.Body.String())
+		}
+		if runs.Load() != 2 {
+			t.Errorf("expected 2 runs, got %d", runs.Load())
+		}
+	})
+
+	t.Run("queued clicks given up are answered with an error", func(t *testing.T) {
+		mux, runs, started, release := setup(ConcurrencyQueue)
+		defer close(release)
+
+		first := click(mux, "/slow")
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/slow", nil).WithContext(ctx))
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get(ConcurrencyHeader) != "abandoned" {
+			t.Errorf("expected abandoned click, got %d %q", rec.Code, rec.Header().Get(ConcurrencyHeader))
+		}
+
+		release <- struct{}{}
+		<-first
+		if runs.Load() != 1 {
+			t.Errorf("expected 1 run, got %d", runs.Load())
+		}
+	})
+
+	t.Run("coalesce shares the running response", func(t *testing.T) {
+		mux, runs, started, release := setup(ConcurrencyCoalesce)
+
+		first := click(mux, "/slow")
+		<-started
+		second := click(mux, "/slow")
+
+		time.Sleep(20 * time.Millisecond) // let the second click join
+		close(release)
+
+		a, b := <-first, <-second
+		if a.Header().Get(ConcurrencyHeader) != "ran" || b.Header().Get(ConcurrencyHeader) != "coalesced" {
+			t.Errorf("unexpected decisions: %q %q", a.Header().Get(ConcurrencyHeader), b.Header().Get(ConcurrencyHeader))
+		}
+		if a.Body.String() != b.Body.String() || b.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("expected same response, got %s and %s", a.Body.String(), b.Body.String())
+		}
+		if runs.Load() != 1 {
+			t.Errorf("expected 1 run, got %d", runs.Load())
+		}
+	})
+
+	t.Run("policy is validated", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "A", Type: ItemTypeCallback, OnClick: "/a", Handler: noop, Concurrency: "once"},
+				{Title: "B", Type: ItemTypeToggle, OnClick: "/b", OnToggle: func(context.Context, bool) error { return nil }, Concurrency: ConcurrencyDrop},
+			},
+		}
+
+		err := m.Validate()
+		for _, w := range []string{`items[0]: unknown concurrency policy "once"`, "items[1]: only callback items can have a concurrency policy"} {
+			if err == nil || !strings.Contains(err.Error(), w) {
+				t.Errorf("expected error to contain %q, got:\n%v", w, err)
+			}
+		}
+	})
+}
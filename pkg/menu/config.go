@@ -0,0 +1,266 @@
+package menu
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Registry binds the handler and provider names used in a menu file to their Go implementations.
+// Callback items can run an external command instead of using a handler (see Command)
+// and submenu items can provide their sub-items from an xbar/SwiftBar plugin (see Plugin).
+// Names are looked up by item type: callback items in Handlers (or Jobs, for a job), toggle items
+// in Toggles, radio items in Selects and items with a provider in Providers. Callback items may also use
+// one of the built-in handlers (see BuiltinHandlers) when the name is not registered.
+type Registry struct {
+	// Handlers are the handlers available to callback items.
+	Handlers map[string]http.Handler
+
+	// Toggles are the handlers available to toggle items.
+	Toggles map[string]ToggleHandler
+
+	// Selects are the handlers available to radio items.
+	Selects map[string]SelectHandler
+
+	// Providers are the providers available to submenu items.
+	Providers map[string]Provider
+
+	// Jobs are the jobs available to callback items that run in the background.
+	Jobs map[string]JobFunc
+}
+
+// BuiltinHandlers returns the names of the handlers available to callback items without registration:
+//   - echo: responds with the method and path of the request
+//   - noop: responds with an empty JSON object
+func BuiltinHandlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		"echo": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]string{
+				"method": r.Method,
+				"url":    r.URL.Path,
+			})
+		}),
+		"noop": http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, struct{}{})
+		}),
+	}
+}
+
+// fileMenu is the schema of a menu file.
+type fileMenu struct {
+	Title           string        `yaml:"title"`
+	Description     string        `yaml:"description"`
+	Version         string        `yaml:"version"`
+	RefreshInterval time.Duration `yaml:"refreshInterval"`
+	Items           []fileItem    `yaml:"items"`
+}
+
+// fileItem is the schema of a menu item in a menu file.
+// Handler and Provider are names resolved through the Registry.
+type fileItem struct {
+	ID              string        `yaml:"id"`
+	Type            ItemType      `yaml:"type"`
+	Title           string        `yaml:"title"`
+	Description     string        `yaml:"description"`
+	OnClick         string        `yaml:"onClick"`
+	Method          string        `yaml:"method"`
+	Shortcut        string        `yaml:"shortcut"`
+	Disabled        bool          `yaml:"disabled"`
+	Handler         string        `yaml:"handler"`
+	Job             string        `yaml:"job"`
+	Concurrency     Concurrency   `yaml:"concurrency"`
+	Checked         bool          `yaml:"checked"`
+	Value           string        `yaml:"value"`
+	Provider        string        `yaml:"provider"`
+	ProviderTimeout time.Duration `yaml:"providerTimeout"`
+	Plugin          string        `yaml:"plugin"`
+	Command         *fileCommand  `yaml:"command"`
+	Items           []fileItem    `yaml:"items"`
+}
+
+// fileCommand is the schema of the command run by a callback item in a menu file.
+type fileCommand struct {
+	Name    string            `yaml:"name"`
+	Args    []string          `yaml:"args"`
+	Dir     string            `yaml:"dir"`
+	Env     map[string]string `yaml:"env"`
+	Timeout time.Duration     `yaml:"timeout"`
+	Async   bool              `yaml:"async"`
+}
+
+// Load reads a menu definition from a YAML or JSON file and binds its handlers using the registry.
+// See Parse for details.
+func Load(path string, reg Registry) (*Menu, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read menu file %s: %w", path, err)
+	}
+
+	m, err := Parse(b, reg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid menu file %s: %w", path, err)
+	}
+
+	slog.Info("menu loaded", "path", path, "items", len(m.Items))
+
+	return m, nil
+}
+
+// Parse decodes a menu definition in YAML or JSON (a subset of YAML) and binds the handler
+// and provider names of its items using the registry. Unknown fields are rejected.
+// The returned menu is validated; every unknown name and validation problem is
+// returned together as a single joined error.
+func Parse(data []byte, reg Registry) (*Menu, error) {
+	var f fileMenu
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode menu: %w", err)
+	}
+
+	b := &binder{reg: reg, builtins: BuiltinHandlers()}
+
+	m := &Menu{
+		Title:           f.Title,
+		Description:     f.Description,
+		Version:         f.Version,
+		RefreshInterval: f.RefreshInterval,
+		Items:           b.items("items", f.Items),
+	}
+
+	if len(b.errs) > 0 {
+		return nil, errors.Join(b.errs...)
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// binder converts menu file items into menu items, resolving names through the registry.
+type binder struct {
+	reg      Registry
+	builtins map[string]http.Handler
+	errs     []error
+}
+
+// items converts a list of sibling file items located under prefix.
+func (b *binder) items(prefix string, in []fileItem) []Item {
+	if in == nil {
+		return nil
+	}
+
+	out := make([]Item, len(in))
+	for i := range in {
+		out[i] = b.item(fmt.Sprintf("%s[%d]", prefix, i), &in[i])
+	}
+
+	return out
+}
+
+// item converts a single file item and its sub-items.
+func (b *binder) item(at string, f *fileItem) Item {
+	item := Item{
+		ID:              f.ID,
+		Type:            f.Type,
+		Title:           f.Title,
+		Description:     f.Description,
+		OnClick:         f.OnClick,
+		Method:          f.Method,
+		Shortcut:        f.Shortcut,
+		Disabled:        f.Disabled,
+		Checked:         f.Checked,
+		Value:           f.Value,
+		Concurrency:     f.Concurrency,
+		ProviderTimeout: f.ProviderTimeout,
+		Items:           b.items(at+".items", f.Items),
+	}
+
+	if f.Provider != "" {
+		item.Provider = lookup(b, at, "provider", f.Provider, b.reg.Providers)
+	}
+
+	if f.Plugin != "" {
+		if f.Provider != "" {
+			b.errs = append(b.errs, fmt.Errorf("%s: item cannot have both a provider and a plugin", at))
+		}
+		item.Provider = NewPlugin(f.Plugin)
+	}
+
+	if f.Job != "" {
+		if f.Type != ItemTypeCallback {
+			b.errs = append(b.errs, fmt.Errorf("%s: job is only valid for callback items", at))
+		}
+		item.Job = lookup(b, at, "job", f.Job, b.reg.Jobs)
+	}
+
+	if f.Command != nil {
+		if f.Type != ItemTypeCallback || f.Handler != "" || f.Job != "" {
+			b.errs = append(b.errs, fmt.Errorf("%s: command is only valid for callback items without a handler or job", at))
+		}
+		if f.Command.Async {
+			item.Job = CommandJob(f.Command.command())
+		} else {
+			item.Handler = CommandHandler(f.Command.command())
+		}
+	}
+
+	if f.Handler == "" {
+		return item
+	}
+
+	switch f.Type {
+	case ItemTypeCallback:
+		if h, ok := b.reg.Handlers[f.Handler]; ok {
+			item.Handler = h
+		} else {
+			item.Handler = lookup(b, at, "handler", f.Handler, b.builtins)
+		}
+	case ItemTypeToggle:
+		item.OnToggle = lookup(b, at, "toggle handler", f.Handler, b.reg.Toggles)
+	case ItemTypeRadio:
+		item.OnSelect = lookup(b, at, "select handler", f.Handler, b.reg.Selects)
+	default:
+		b.errs = append(b.errs, fmt.Errorf("%s: %q items do not take a handler", at, f.Type))
+	}
+
+	return item
+}
+
+// command converts the file command into a Command, with its environment in a stable order.
+func (f *fileCommand) command() Command {
+	env := make([]string, 0, len(f.Env))
+	for _, k := range slices.Sorted(maps.Keys(f.Env)) {
+		env = append(env, k+"="+f.Env[k])
+	}
+
+	return Command{
+		Name:    f.Name,
+		Args:    f.Args,
+		Dir:     f.Dir,
+		Env:     env,
+		Timeout: f.Timeout,
+	}
+}
+
+// lookup returns the named value from the registry map, recording an error if it is not registered.
+func lookup[T any](b *binder, at, kind, name string, registered map[string]T) T {
+	v, ok := registered[name]
+	if !ok {
+		b.errs = append(b.errs, fmt.Errorf("%s: unknown %s %q", at, kind, name))
+	}
+
+	return v
+}
@@ -0,0 +1,215 @@
+package menu
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	reg := Registry{
+		Handlers:  map[string]http.Handler{"hello": noop},
+		Toggles:   map[string]ToggleHandler{"dnd": func(context.Context, bool) error { return nil }},
+		Selects:   map[string]SelectHandler{"env": func(context.Context, string) error { return nil }},
+		Providers: map[string]Provider{"live": ProviderFunc(func(context.Context) ([]Item, error) { return nil, nil })},
+	}
+
+	t.Run("yaml binds registered and built-in handlers", func(t *testing.T) {
+		m, err := Parse([]byte(`
+title: Test
+refreshInterval: 1m
+items:
+  - title: Hello
+    id: greetings/hello
+    type: callback
+    onClick: /hello
+    handler: hello
+    shortcut: cmd+h
+    concurrency: drop
+  - title: Echo
+    type: callback
+    onClick: /echo
+    handler: echo
+  - type: separator
+  - title: DND
+    type: toggle
+    onClick: /dnd
+    handler: dnd
+    checked: true
+  - title: Env
+    type: radio
+    onClick: /env
+    handler: env
+    items:
+      - title: Dev
+      - title: Prod
+        checked: true
+  - title: Live
+    provider: live
+    providerTimeout: 3s
+`), reg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if m.RefreshInterval != time.Minute {
+			t.Errorf("expected refresh interval 1m, got %v", m.RefreshInterval)
+		}
+		if m.Items[0].Handler == nil || m.Items[1].Handler == nil {
+			t.Error("expected callback handlers to be bound")
+		}
+		if m.Items[0].ID != "greetings/hello" {
+			t.Errorf("expected explicit id, got %q", m.Items[0].ID)
+		}
+		if m.Items[0].Concurrency != ConcurrencyDrop {
+			t.Errorf("expected drop concurrency policy, got %q", m.Items[0].Concurrency)
+		}
+		if m.Items[3].OnToggle == nil || !m.Items[3].Checked {
+			t.Error("expected toggle to be bound and checked")
+		}
+		if m.Items[4].OnSelect == nil {
+			t.Error("expected radio to be bound")
+		}
+		if m.Items[5].Provider == nil || m.Items[5].ProviderTimeout != 3*time.Second {
+			t.Error("expected provider to be bound with its timeout")
+		}
+	})
+
+	t.Run("json is accepted", func(t *testing.T) {
+		m, err := Parse([]byte(`{"title": "Test", "items": [{"title": "GitHub", "type": "link", "onClick": "https://github.com"}]}`), reg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(m.Items) != 1 || m.Items[0].OnClick != "https://github.com" {
+			t.Errorf("unexpected items: %+v", m.Items)
+		}
+	})
+
+	t.Run("unknown names and invalid items are reported together", func(t *testing.T) {
+		_, err := Parse([]byte(`
+title: Test
+items:
+  - title: Hello
+    type: callback
+    onClick: /hello
+    handler: helo
+  - title: Live
+    provider: dead
+`), reg)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		for _, w := range []string{`items[0]: unknown handler "helo"`, `items[1]: unknown provider "dead"`} {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("expected error to contain %q, got:\n%v", w, err)
+			}
+		}
+	})
+
+	t.Run("commands are bound to callback items only", func(t *testing.T) {
+		m, err := Parse([]byte(`
+title: Test
+items:
+  - title: Uptime
+    type: callback
+    onClick: /uptime
+    command:
+      name: uptime
+      env:
+        LANG: C
+      timeout: 2s
+`), reg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if m.Items[0].Handler == nil {
+			t.Error("expected command handler to be bound")
+		}
+
+		_, err = Parse([]byte(`
+title: Test
+items:
+  - title: Uptime
+    type: link
+    onClick: https://github.com
+    command:
+      name: uptime
+`), reg)
+		if err == nil || !strings.Contains(err.Error(), "items[0]: command is only valid for callback items") {
+			t.Errorf("expected command on link to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("jobs and async commands are bound as jobs", func(t *testing.T) {
+		reg := reg
+		reg.Jobs = map[string]JobFunc{"deploy": func(context.Context, Progress) (ActionResult, error) { return ActionResult{}, nil }}
+
+		m, err := Parse([]byte(`
+title: Test
+items:
+  - title: Deploy
+    type: callback
+    onClick: /deploy
+    job: deploy
+  - title: Backup
+    type: callback
+    onClick: /backup
+    command:
+      name: backup.sh
+      async: true
+`), reg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i, item := range m.Items {
+			if item.Job == nil || item.Handler != nil {
+				t.Errorf("items[%d]: expected job to be bound", i)
+			}
+		}
+	})
+
+	t.Run("plugins are bound as providers", func(t *testing.T) {
+		m, err := Parse([]byte("title: Test\nitems:\n  - title: CPU\n    plugin: plugins/cpu.10s.sh\n"), reg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p, ok := m.Items[0].Provider.(*Plugin); !ok || p.Interval != 10*time.Second {
+			t.Errorf("expected plugin provider, got %#v", m.Items[0].Provider)
+		}
+
+		_, err = Parse([]byte("title: Test\nitems:\n  - title: CPU\n    provider: live\n    plugin: cpu.sh\n"), reg)
+		if err == nil || !strings.Contains(err.Error(), "items[0]: item cannot have both a provider and a plugin") {
+			t.Errorf("expected provider and plugin to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("unknown fields are rejected", func(t *testing.T) {
+		if _, err := Parse([]byte("title: Test\nitems:\n  - titel: Typo\n"), reg); err == nil {
+			t.Error("expected error for unknown field")
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "menu.yaml")
+	if err := os.WriteFile(path, []byte("title: Test\nitems:\n  - title: Noop\n    type: callback\n    onClick: /noop\n    handler: noop\n"), 0o600); err != nil {
+		t.Fatalf("failed to write menu file: %v", err)
+	}
+
+	m, err := Load(path, Registry{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Title != "Test" || m.Items[0].Handler == nil {
+		t.Errorf("unexpected menu: %+v", m)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml"), Registry{}); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
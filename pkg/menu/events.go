@@ -0,0 +1,164 @@
+package menu
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// EventsPath is the server path of the Server-Sent Events stream of menu changes.
+	EventsPath = "/events"
+
+	// EventMenuChanged is the name of the event sent when the menu changes.
+	// Its data is a JSON object with the new menu revision (e.g. {"revision":3}).
+	EventMenuChanged = "menu-changed"
+
+	// DefaultEventsKeepAlive is how often a comment is sent on idle event streams
+	// so that clients and proxies do not consider the connection dead.
+	DefaultEventsKeepAlive = 15 * time.Second
+
+	// eventsRetry is the reconnection delay, in milliseconds, suggested to clients.
+	eventsRetry = 3000
+)
+
+// broker fans out menu change notifications to the connected event stream subscribers.
+// The zero value is ready to use.
+type broker struct {
+	mu       sync.Mutex
+	subs     map[chan struct{}]struct{}
+	closed   bool
+	revision atomic.Uint64
+}
+
+// subscribe registers a new subscriber. The returned channel receives a value when the
+// menu changes and is closed when the broker is closed. Returns nil if the broker is closed.
+func (b *broker) subscribe() chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+
+	if b.subs == nil {
+		b.subs = map[chan struct{}]struct{}{}
+	}
+
+	// Buffer of one coalesces bursts of changes: subscribers always read the latest revision.
+	ch := make(chan struct{}, 1)
+	b.subs[ch] = struct{}{}
+
+	return ch
+}
+
+// unsubscribe removes a subscriber.
+func (b *broker) unsubscribe(ch chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subs, ch)
+}
+
+// publish bumps the revision and notifies every subscriber.
+func (b *broker) publish() uint64 {
+	rev := b.revision.Add(1)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- struct{}{}:
+		default: // subscriber already has a pending notification
+		}
+	}
+
+	return rev
+}
+
+// close disconnects every subscriber and rejects new ones.
+func (b *broker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subs {
+		close(ch)
+		delete(b.subs, ch)
+	}
+}
+
+// Publish notifies connected clients that the menu has changed so they fetch it again.
+// It is called automatically when toggle or radio state changes, when the menu is changed or swapped,
+// and when a provider result changes on a background refresh (see RefreshInterval);
+// call it after changing anything else the menu depends on.
+func (m *Menu) Publish() {
+	rev := m.events.publish()
+	slog.Debug("menu change published", "revision", rev)
+}
+
+// EventsHandler returns an HTTP handler that streams menu changes as Server-Sent Events.
+// A "menu-changed" event carrying the current revision is sent on connect and after every change.
+func (m *Menu) EventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ch := m.events.subscribe()
+		if ch == nil {
+			http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer m.events.unsubscribe(ch)
+
+		// Event streams outlive the server write timeout.
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			slog.Debug("failed to clear write deadline for event stream", "error", err)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		slog.Info("event stream connected", "remote", r.RemoteAddr)
+		defer slog.Info("event stream disconnected", "remote", r.RemoteAddr)
+
+		send := func(format string, args ...any) bool {
+			if _, err := fmt.Fprintf(w, format, args...); err != nil {
+				return false
+			}
+			return rc.Flush() == nil
+		}
+
+		if !send("retry: %d\n\n", eventsRetry) || !m.sendChanged(send) {
+			return
+		}
+
+		keepAlive := time.NewTicker(DefaultEventsKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case _, ok := <-ch:
+				if !ok || !m.sendChanged(send) {
+					return
+				}
+			case <-keepAlive.C:
+				if !send(": keep-alive\n\n") {
+					return
+				}
+			}
+		}
+	})
+}
+
+// sendChanged sends a menu-changed event with the current revision.
+func (m *Menu) sendChanged(send func(format string, args ...any) bool) bool {
+	rev := m.events.revision.Load()
+	return send("event: %s\nid: %d\ndata: {\"revision\":%d}\n\n", EventMenuChanged, rev, rev)
+}
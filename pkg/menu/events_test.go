@@ -0,0 +1,94 @@
+package menu
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nextEvent reads the stream until the next event with data and returns its name and data.
+func nextEvent(t *testing.T, sc *bufio.Scanner) (string, string) {
+	t.Helper()
+
+	var name string
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			return name, strings.TrimPrefix(line, "data: ")
+		}
+	}
+
+	t.Fatalf("event stream ended: %v", sc.Err())
+	return "", ""
+}
+
+func TestEvents(t *testing.T) {
+	m := &Menu{
+		Title: "Test",
+		Items: []Item{
+			{Title: "DND", Type: ItemTypeToggle, OnClick: "/dnd", OnToggle: func(context.Context, bool) error { return nil }},
+		},
+	}
+
+	mux := serve(m)
+	mux.Handle(EventsPath, m.EventsHandler())
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+EventsPath, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect to event stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected Content-Type text/event-stream, got %q", ct)
+	}
+
+	sc := bufio.NewScanner(resp.Body)
+
+	name, data := nextEvent(t, sc)
+	if name != EventMenuChanged || data != `{"revision":0}` {
+		t.Errorf("expected initial %s event with revision 0, got %s %s", EventMenuChanged, name, data)
+	}
+
+	click, err := http.Post(srv.URL+"/dnd", "", nil)
+	if err != nil {
+		t.Fatalf("failed to click toggle: %v", err)
+	}
+	click.Body.Close()
+
+	name, data = nextEvent(t, sc)
+	if name != EventMenuChanged || data != `{"revision":1}` {
+		t.Errorf("expected %s event with revision 1 after toggle, got %s %s", EventMenuChanged, name, data)
+	}
+
+	m.Publish()
+
+	if _, data = nextEvent(t, sc); data != `{"revision":2}` {
+		t.Errorf("expected revision 2 after publish, got %s", data)
+	}
+
+	m.events.close()
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			t.Errorf("expected stream to be closed, got %q", line)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package menu
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// idPattern is the format of item IDs: slash-separated segments of letters, digits, '.', '_' and '-'.
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)*$`)
+
+// itemIDs returns the IDs of sibling items under the parent ID (empty at the top level).
+// Items without an explicit ID get one derived from their position in the tree: the parent ID
+// and a slug of their title (or of their type when they have no title, e.g. separators),
+// suffixed with "-2", "-3"... when that ID is already taken by an earlier sibling or by the
+// explicit ID of any sibling (e.g. "Restart", "Restart 2" and "Restart" get "restart",
+// "restart-2" and "restart-3").
+func itemIDs(parent string, items []Item) []string {
+	ids := make([]string, len(items))
+	taken := map[string]bool{}
+
+	for i := range items {
+		if items[i].ID != "" {
+			ids[i] = items[i].ID
+			taken[items[i].ID] = true
+		}
+	}
+
+	for i := range items {
+		if ids[i] != "" {
+			continue
+		}
+
+		s := slug(items[i].Title)
+		if s == "" {
+			s = slug(string(items[i].Type))
+		}
+		if s == "" {
+			s = "item"
+		}
+		if parent != "" {
+			s = parent + "/" + s
+		}
+
+		id := s
+		for n := 2; taken[id]; n++ {
+			id = s + "-" + strconv.Itoa(n)
+		}
+
+		taken[id] = true
+		ids[i] = id
+	}
+
+	return ids
+}
+
+// slug returns s in lower case with every run of characters other than letters and digits
+// replaced by a single "-" (e.g. "Item 2 (submenu)" becomes "item-2-submenu").
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	return b.String()
+}
+
+// ids validates the IDs of the items under prefix and their sub-items, including radio options:
+// explicit IDs must be well-formed and every ID must be unique in the whole tree.
+func (v *validator) ids(prefix, parent string, items []Item) {
+	for i, id := range itemIDs(parent, items) {
+		at := fmt.Sprintf("%s[%d]", prefix, i)
+
+		if items[i].ID != "" && !idPattern.MatchString(items[i].ID) {
+			v.add(at, "invalid id %q (expected letters, digits, '.', '_' or '-' separated by '/')", items[i].ID)
+		}
+
+		if prev, ok := v.byID[id]; ok {
+			v.add(at, "duplicate id %q (also used by %s)", id, prev)
+		} else {
+			v.byID[id] = at
+		}
+
+		v.ids(at+".items", id, items[i].Items)
+	}
+}
+
+// Walk calls fn for every item of the menu as it is served to clients, with its ID set,
+// depth first in menu order, including the last good result of every provider
+// (providers are not called) and radio options. It stops when fn returns false.
+func (m *Menu) Walk(fn func(item Item) bool) {
+	walk(m.view().Items, fn)
+}
+
+// walk calls fn for every item and its sub-items, returning false as soon as fn does.
+func walk(items []Item, fn func(item Item) bool) bool {
+	for _, item := range items {
+		if !fn(item) || !walk(item.Items, fn) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Find returns the item with the given ID as it is served to clients (see Walk).
+// It returns false if no item has that ID.
+func (m *Menu) Find(id string) (Item, bool) {
+	var (
+		found Item
+		ok    bool
+	)
+
+	m.Walk(func(item Item) bool {
+		if item.ID == id {
+			found, ok = item, true
+		}
+		return !ok
+	})
+
+	return found, ok
+}
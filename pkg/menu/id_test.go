@@ -0,0 +1,153 @@
+package menu
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestItemIDs(t *testing.T) {
+	m := &Menu{
+		Title: "Test",
+		Items: []Item{
+			{Title: "Actions", Type: ItemTypeHeader},
+			{Title: "Say Hello!", Type: ItemTypeCallback, OnClick: "/hello", Handler: noop},
+			{Type: ItemTypeSeparator},
+			{Type: ItemTypeSeparator},
+			{ID: "tools", Title: "Item 2 (submenu)", Items: []Item{
+				{Title: "Sub", Type: ItemTypeCallback, OnClick: "/sub1", Handler: noop},
+				{Title: "Sub", Type: ItemTypeCallback, OnClick: "/sub2", Handler: noop},
+			}},
+			{Title: "Env", Type: ItemTypeRadio, OnClick: "/env", OnSelect: func(context.Context, string) error { return nil }, Items: []Item{
+				{Title: "Dev"},
+				{Title: "Prod", ID: "production"},
+			}},
+			{Title: "Live", Provider: ProviderFunc(func(context.Context) ([]Item, error) {
+				return []Item{{Title: "Fetched", Type: ItemTypeHeader}}, nil
+			})},
+		},
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("expected valid menu, got: %v", err)
+	}
+
+	var served Menu
+	get(t, serve(m), "/", &served)
+
+	var ids []string
+	walk(served.Items, func(item Item) bool {
+		ids = append(ids, item.ID)
+		return true
+	})
+
+	want := []string{
+		"actions", "say-hello", "separator", "separator-2",
+		"tools", "tools/sub", "tools/sub-2",
+		"env", "env/dev", "production",
+		"live", "live/fetched",
+	}
+	if !slices.Equal(ids, want) {
+		t.Errorf("expected ids %v, got %v", want, ids)
+	}
+
+	t.Run("walk and find", func(t *testing.T) {
+		var visited []string
+		m.Walk(func(item Item) bool {
+			visited = append(visited, item.ID)
+			return item.ID != "tools/sub"
+		})
+		if !slices.Equal(visited, want[:6]) {
+			t.Errorf("expected walk to stop at tools/sub, got %v", visited)
+		}
+
+		item, ok := m.Find("tools/sub-2")
+		if !ok || item.OnClick != "/sub2" {
+			t.Errorf("expected to find /sub2, got %+v", item)
+		}
+
+		if item, ok := m.Find("live/fetched"); !ok || item.Title != "Fetched" {
+			t.Errorf("expected to find provided item, got %+v", item)
+		}
+
+		if _, ok := m.Find("missing"); ok {
+			t.Error("expected missing item not to be found")
+		}
+	})
+}
+
+func TestValidateIDs(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []Item
+		want  string
+	}{
+		{
+			name: "duplicate explicit ids",
+			items: []Item{
+				{ID: "a", Title: "One", Type: ItemTypeHeader},
+				{Title: "Sub", Items: []Item{{ID: "a", Title: "Two", Type: ItemTypeHeader}}},
+			},
+			want: `items[1].items[0]: duplicate id "a" (also used by items[0])`,
+		},
+		{
+			name: "explicit id used by a derived one elsewhere in the tree",
+			items: []Item{
+				{Title: "Hello", Type: ItemTypeHeader},
+				{Title: "Sub", Items: []Item{{ID: "hello", Title: "Other", Type: ItemTypeHeader}}},
+			},
+			want: `items[1].items[0]: duplicate id "hello" (also used by items[0])`,
+		},
+		{
+			name:  "invalid id",
+			items: []Item{{ID: "has space", Title: "One", Type: ItemTypeHeader}},
+			want:  `items[0]: invalid id "has space"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Menu{Title: "Test", Items: tt.items}).Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("derived ids skip ids taken by siblings", func(t *testing.T) {
+		items := []Item{
+			{Title: "Restart", Type: ItemTypeHeader},
+			{Title: "Restart 2", Type: ItemTypeHeader},
+			{Title: "Restart", Type: ItemTypeHeader},
+			{Title: "Hello", Type: ItemTypeHeader},
+			{ID: "hello", Title: "Other", Type: ItemTypeHeader},
+		}
+		if err := (&Menu{Title: "Test", Items: items}).Validate(); err != nil {
+			t.Fatalf("expected valid menu, got: %v", err)
+		}
+
+		want := []string{"restart", "restart-2", "restart-3", "hello-2", "hello"}
+		if ids := itemIDs("", items); !slices.Equal(ids, want) {
+			t.Errorf("expected ids %v, got %v", want, ids)
+		}
+	})
+
+	t.Run("provided items must not reuse static ids", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{ID: "taken", Title: "Static", Type: ItemTypeHeader},
+				{Title: "Live", Provider: ProviderFunc(func(context.Context) ([]Item, error) {
+					return []Item{{ID: "taken", Title: "Dynamic", Type: ItemTypeHeader}}, nil
+				})},
+			},
+		}
+
+		var served Menu
+		get(t, serve(m), "/", &served)
+
+		if len(served.Items[1].Items) != 0 {
+			t.Errorf("expected provider result to be rejected, got %+v", served.Items[1].Items)
+		}
+	})
+}
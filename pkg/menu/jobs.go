@@ -0,0 +1,400 @@
+package menu
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// JobsPath is the server path listing the jobs started by callback items.
+	// Single jobs are served at JobsPath/{id}.
+	JobsPath = "/jobs"
+
+	// DefaultMaxJobs is how many jobs can run at the same time when the menu does not set MaxJobs.
+	DefaultMaxJobs = 4
+
+	// maxFinishedJobs is how many finished jobs are kept for clients to read their result.
+	maxFinishedJobs = 100
+)
+
+const (
+	// JobQueued is the status of a job waiting for the previous job of its item to finish
+	// (see ConcurrencyQueue).
+	JobQueued JobStatus = "queued"
+	// JobRunning is the status of a job that has not finished yet.
+	JobRunning JobStatus = "running"
+	// JobSucceeded is the status of a job that returned without an error.
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed is the status of a job that returned an error.
+	JobFailed JobStatus = "failed"
+	// JobCanceled is the status of a job canceled by a client or by the server shutting down.
+	JobCanceled JobStatus = "canceled"
+)
+
+// JobStatus represents the status of a job.
+type JobStatus string
+
+// finished reports whether a job with the status has finished, rather than being queued or running.
+func (s JobStatus) finished() bool {
+	return s != JobQueued && s != JobRunning
+}
+
+// Progress reports how far a job is, as a fraction between 0 and 1, with an optional message.
+type Progress func(fraction float64, message string)
+
+// JobFunc is the work done by a callback item in the background. The click returns immediately
+// with the started job; ctx is canceled when the job is canceled or the server shuts down.
+// The returned result (or the error) is performed by the client once the job finishes.
+type JobFunc func(ctx context.Context, progress Progress) (ActionResult, error)
+
+// Job is the status of a job started by a callback item, as served to clients.
+type Job struct {
+	// ID identifies the job, it is served at JobsPath/{id}.
+	ID string `json:"id"`
+
+	// Item is the onClick path of the item that started the job.
+	Item string `json:"item"`
+
+	// Status of the job.
+	Status JobStatus `json:"status"`
+
+	// Progress of the job, as a fraction between 0 and 1.
+	Progress float64 `json:"progress"`
+
+	// Message is the last progress message reported by the job.
+	Message string `json:"message,omitempty"`
+
+	// Started is when the job started or, while it is queued, when it was queued.
+	Started time.Time `json:"started"`
+
+	// Ended is when the job finished, if it has.
+	Ended *time.Time `json:"ended,omitempty"`
+
+	// Result is what the client should do now that the job has finished.
+	Result *ActionResult `json:"result,omitempty"`
+}
+
+// jobs holds the queued, running and recently finished jobs. The zero value is ready to use.
+type jobs struct {
+	mu      sync.Mutex
+	byID    map[string]*job
+	order   []string // job IDs in start order
+	running int      // jobs queued or running
+	closed  bool
+}
+
+// job is a single job along with what is needed to cancel it.
+type job struct {
+	Job
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+// start runs fn in the background as a job started by the item at path, calling done, if not nil,
+// once fn has returned. When wait is not nil, the job is queued until wait returns; fn is not called,
+// and the job is canceled, if wait returns false. Queued jobs count toward the limit.
+// Returns false if limit jobs are already queued or running or the server is shutting down.
+func (js *jobs) start(path string, limit int, fn JobFunc, wait func(ctx context.Context) bool, done func()) (Job, bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	if js.closed || js.running >= limit {
+		return Job{}, false
+	}
+
+	status := JobRunning
+	if wait != nil {
+		status = JobQueued
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		Job: Job{
+			ID:      newJobID(),
+			Item:    path,
+			Status:  status,
+			Started: time.Now().UTC(),
+		},
+		cancel: cancel,
+	}
+
+	if js.byID == nil {
+		js.byID = map[string]*job{}
+	}
+	js.byID[j.ID] = j
+	js.order = append(js.order, j.ID)
+	js.running++
+
+	go js.run(ctx, j, fn, wait, done)
+
+	return j.Job, true
+}
+
+// run waits for the job to leave the queue, if queued, calls the job function and records how it
+// finished. Done, if not nil, is called before the job is reported as finished, so that whatever
+// it releases is free by then.
+func (js *jobs) run(ctx context.Context, j *job, fn JobFunc, wait func(ctx context.Context) bool, done func()) {
+	defer j.cancel()
+
+	if wait != nil {
+		if !wait(ctx) {
+			js.finish(j, ActionResult{}, ctx.Err())
+			return
+		}
+
+		js.mu.Lock()
+		j.Status, j.Started = JobRunning, time.Now().UTC()
+		js.mu.Unlock()
+	}
+
+	slog.Info("job started", "id", j.ID, "item", j.Item)
+
+	res, err := js.call(ctx, j, fn)
+	if done != nil {
+		done()
+	}
+
+	js.finish(j, res, err)
+}
+
+// finish records how the job finished.
+func (js *jobs) finish(j *job, res ActionResult, err error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	switch {
+	case j.canceled:
+		j.Status = JobCanceled
+		res = ActionResult{Error: "job canceled"}
+	case err != nil:
+		j.Status = JobFailed
+		res = ActionResult{Error: err.Error()}
+	default:
+		j.Status = JobSucceeded
+		j.Progress = 1
+	}
+
+	res.Version = ActionResultVersion
+	ended := time.Now().UTC()
+	j.Ended, j.Result = &ended, &res
+	js.running--
+	js.prune()
+
+	slog.Info("job finished", "id", j.ID, "item", j.Item, "status", j.Status, "duration", ended.Sub(j.Started))
+}
+
+// call calls the job function, turning a panic into an error so a faulty job cannot crash the server.
+func (js *jobs) call(ctx context.Context, j *job, fn JobFunc) (res ActionResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return fn(ctx, func(fraction float64, message string) {
+		js.mu.Lock()
+		defer js.mu.Unlock()
+
+		j.Progress = min(max(fraction, 0), 1)
+		j.Message = message
+	})
+}
+
+// prune forgets the oldest finished jobs beyond maxFinishedJobs. Must be called with mu held.
+func (js *jobs) prune() {
+	finished := len(js.order) - js.running
+	for i := 0; i < len(js.order) && finished > maxFinishedJobs; {
+		if j := js.byID[js.order[i]]; j.Status.finished() {
+			delete(js.byID, j.ID)
+			js.order = slices.Delete(js.order, i, i+1)
+			finished--
+			continue
+		}
+		i++
+	}
+}
+
+// list returns every job, most recently started first.
+func (js *jobs) list() []Job {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	out := make([]Job, 0, len(js.order))
+	for i := len(js.order) - 1; i >= 0; i-- {
+		out = append(out, js.byID[js.order[i]].Job)
+	}
+
+	return out
+}
+
+// get returns the job with the given ID.
+func (js *jobs) get(id string) (Job, bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	j, ok := js.byID[id]
+	if !ok {
+		return Job{}, false
+	}
+
+	return j.Job, true
+}
+
+// cancel cancels the queued or running job with the given ID.
+// Returns the job and false if it does not exist or has already finished.
+func (js *jobs) cancel(id string) (Job, bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	j, ok := js.byID[id]
+	if !ok || j.Status.finished() {
+		return Job{}, false
+	}
+
+	j.canceled = true
+	j.cancel()
+
+	return j.Job, true
+}
+
+// close cancels every queued or running job and refuses new ones.
+func (js *jobs) close() {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	js.closed = true
+	for _, j := range js.byID {
+		if !j.Status.finished() {
+			j.canceled = true
+			j.cancel()
+		}
+	}
+}
+
+// newJobID returns a random job ID.
+func newJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b) // never returns an error
+	return hex.EncodeToString(b)
+}
+
+// jobHandler returns the HTTP handler for a callback item that runs as a job.
+// The click responds with 202 and an ActionResult carrying the started job,
+// or 429 when MaxJobs jobs are already queued or running.
+//
+// The concurrency policy of the item applies to its jobs rather than to the clicks, which return
+// right away: the lane of the item is held until the job finishes, so that drop ignores clicks
+// while a job runs, queue responds with a queued job that starts once the previous ones have
+// finished, and coalesce responds with the running job.
+func (m *Menu) jobHandler(item *Item) http.Handler {
+	path, fn, policy := item.OnClick, item.Job, item.Concurrency
+
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		limit := m.MaxJobs
+		if limit <= 0 {
+			limit = DefaultMaxJobs
+		}
+
+		start := func(decision string, wait func(context.Context) bool, done func()) (Job, bool) {
+			j, ok := m.jobs.start(path, limit, fn, wait, done)
+			if !ok {
+				WriteActionError(w, http.StatusTooManyRequests, fmt.Errorf("too many jobs running (limit %d)", limit))
+				return Job{}, false
+			}
+
+			writeJob(w, decision, j)
+			return j, true
+		}
+
+		ln := m.lanes.lane(path)
+
+		switch policy {
+		case ConcurrencyDrop:
+			if !ln.tryAcquire() {
+				writeDropped(w)
+				return
+			}
+			if _, ok := start(decisionRan, nil, ln.release); !ok {
+				ln.release()
+			}
+		case ConcurrencyQueue:
+			if !ln.tryAcquire() {
+				start(decisionQueued, ln.wait, ln.release)
+				return
+			}
+			if _, ok := start(decisionRan, nil, ln.release); !ok {
+				ln.release()
+			}
+		case ConcurrencyCoalesce:
+			ln.mu.Lock()
+			defer ln.mu.Unlock()
+
+			if j, ok := m.jobs.get(ln.job); ok && !j.Status.finished() {
+				writeJob(w, decisionCoalesced, j)
+				return
+			}
+			if j, ok := start(decisionRan, nil, nil); ok {
+				ln.job = j.ID
+			}
+		default:
+			start("", nil, nil)
+		}
+	})
+}
+
+// writeJob responds to a click with the job it started, or joined, and the concurrency decision, if any.
+func writeJob(w http.ResponseWriter, decision string, j Job) {
+	if decision != "" {
+		w.Header().Set(ConcurrencyHeader, decision)
+	}
+
+	w.Header().Set("Location", JobsPath+"/"+j.ID)
+	WriteAction(w, http.StatusAccepted, ActionResult{Job: &j})
+}
+
+// JobsHandler returns an HTTP handler serving the jobs started by callback items:
+//   - GET JobsPath lists every job, most recently started first
+//   - GET JobsPath/{id} returns a single job
+//   - DELETE JobsPath/{id} cancels a running job
+func (m *Menu) JobsHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+JobsPath, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string][]Job{"jobs": m.jobs.list()})
+	})
+
+	mux.HandleFunc("GET "+JobsPath+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+		j, ok := m.jobs.get(r.PathValue("id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, j)
+	})
+
+	mux.HandleFunc("DELETE "+JobsPath+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		j, ok := m.jobs.cancel(id)
+		if !ok {
+			if _, exists := m.jobs.get(id); exists {
+				writeError(w, http.StatusConflict, "job already finished")
+				return
+			}
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+
+		slog.Info("job cancel requested", "id", id, "item", j.Item)
+		writeJSON(w, http.StatusAccepted, j)
+	})
+
+	return mux
+}
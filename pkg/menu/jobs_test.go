@@ -0,0 +1,254 @@
+package menu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wait polls the job until it has finished.
+func wait(t *testing.T, h http.Handler, id string) Job {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		var j Job
+		if code := get(t, h, JobsPath+"/"+id, &j); code != http.StatusOK {
+			t.Fatalf("expected 200 for job %s, got %d", id, code)
+		}
+		if j.Status.finished() {
+			return j
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+// cancel sends a DELETE for the job and returns the status code.
+func cancel(h http.Handler, id string) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, JobsPath+"/"+id, nil))
+	return rec.Code
+}
+
+func TestJobs(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	m := &Menu{
+		Title:   "Test",
+		MaxJobs: 2,
+		Items: []Item{
+			{Title: "Deploy", Type: ItemTypeCallback, OnClick: "/deploy", Job: func(_ context.Context, progress Progress) (ActionResult, error) {
+				progress(0.5, "halfway")
+				return Notify("Deployed", "v1"), nil
+			}},
+			{Title: "Backup", Type: ItemTypeCallback, OnClick: "/backup", Job: func(ctx context.Context, progress Progress) (ActionResult, error) {
+				progress(0.1, "copying")
+				select {
+				case <-ctx.Done():
+					return ActionResult{}, ctx.Err()
+				case <-release:
+					return ActionResult{}, nil
+				}
+			}},
+			{Title: "Broken", Type: ItemTypeCallback, OnClick: "/broken", Job: func(context.Context, Progress) (ActionResult, error) {
+				return ActionResult{}, errors.New("disk full")
+			}},
+		},
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("expected valid menu, got: %v", err)
+	}
+
+	mux := serve(m)
+	mux.Handle(JobsPath, m.JobsHandler())
+	mux.Handle(JobsPath+"/", m.JobsHandler())
+
+	start := func(t *testing.T, path string) Job {
+		t.Helper()
+
+		var res ActionResult
+		if code := post(t, mux, path, &res); code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d", code)
+		}
+		if res.Job == nil || res.Job.ID == "" || res.Job.Item != path || res.Job.Status != JobRunning {
+			t.Fatalf("expected started job, got %+v", res.Job)
+		}
+
+		return *res.Job
+	}
+
+	t.Run("click returns the job which finishes with its result", func(t *testing.T) {
+		j := wait(t, mux, start(t, "/deploy").ID)
+
+		if j.Status != JobSucceeded || j.Progress != 1 || j.Message != "halfway" || j.Ended == nil {
+			t.Errorf("unexpected finished job: %+v", j)
+		}
+		if j.Result == nil || j.Result.Notification == nil || j.Result.Notification.Title != "Deployed" {
+			t.Errorf("expected job result, got %+v", j.Result)
+		}
+	})
+
+	t.Run("failed job carries the error", func(t *testing.T) {
+		j := wait(t, mux, start(t, "/broken").ID)
+
+		if j.Status != JobFailed || j.Result == nil || j.Result.Error != "disk full" {
+			t.Errorf("unexpected failed job: %+v", j)
+		}
+	})
+
+	t.Run("running jobs are bounded and can be canceled", func(t *testing.T) {
+		first, second := start(t, "/backup"), start(t, "/backup")
+
+		var res ActionResult
+		if code := post(t, mux, "/backup", &res); code != http.StatusTooManyRequests || !strings.Contains(res.Error, "limit 2") {
+			t.Errorf("expected 429 over the limit, got %d %+v", code, res)
+		}
+
+		if code := cancel(mux, first.ID); code != http.StatusAccepted {
+			t.Fatalf("expected 202, got %d", code)
+		}
+		if j := wait(t, mux, first.ID); j.Status != JobCanceled {
+			t.Errorf("expected canceled job, got %s", j.Status)
+		}
+
+		if code := cancel(mux, first.ID); code != http.StatusConflict {
+			t.Errorf("expected 409 for finished job, got %d", code)
+		}
+		if code := cancel(mux, "missing"); code != http.StatusNotFound {
+			t.Errorf("expected 404 for unknown job, got %d", code)
+		}
+
+		var list struct {
+			Jobs []Job `json:"jobs"`
+		}
+		get(t, mux, JobsPath, &list)
+		if len(list.Jobs) != 4 || list.Jobs[0].ID != second.ID || list.Jobs[0].Status != JobRunning || list.Jobs[0].Progress != 0.1 {
+			t.Errorf("expected jobs most recent first, got %+v", list.Jobs)
+		}
+	})
+}
+
+func TestJobConcurrency(t *testing.T) {
+	setup := func(policy Concurrency) (http.Handler, chan struct{}) {
+		release := make(chan struct{})
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "Deploy", Type: ItemTypeCallback, OnClick: "/deploy", Concurrency: policy, Job: func(ctx context.Context, _ Progress) (ActionResult, error) {
+					<-release
+					return ActionResult{}, nil
+				}},
+			},
+		}
+
+		mux := serve(m)
+		mux.Handle(JobsPath+"/", m.JobsHandler())
+		return mux, release
+	}
+
+	// click sends a click and returns its status, concurrency decision and job.
+	click := func(h http.Handler) (int, string, *Job) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/deploy", nil))
+
+		var res ActionResult
+		_ = json.NewDecoder(rec.Body).Decode(&res)
+		return rec.Code, rec.Header().Get(ConcurrencyHeader), res.Job
+	}
+
+	t.Run("drop ignores clicks until the job finishes", func(t *testing.T) {
+		mux, release := setup(ConcurrencyDrop)
+
+		code, decision, first := click(mux)
+		if code != http.StatusAccepted || decision != "ran" || first == nil {
+			t.Fatalf("expected started job, got %d %q %+v", code, decision, first)
+		}
+
+		if code, decision, _ := click(mux); code != http.StatusConflict || decision != "dropped" {
+			t.Errorf("expected click to be dropped while the job runs, got %d %q", code, decision)
+		}
+
+		close(release)
+		wait(t, mux, first.ID)
+
+		if code, decision, second := click(mux); code != http.StatusAccepted || decision != "ran" || second.ID == first.ID {
+			t.Errorf("expected a new job once the first finished, got %d %q %+v", code, decision, second)
+		}
+	})
+
+	t.Run("queue responds at once with a job started after the running one", func(t *testing.T) {
+		mux, release := setup(ConcurrencyQueue)
+
+		_, _, first := click(mux)
+		code, decision, second := click(mux)
+		if code != http.StatusAccepted || decision != "queued" || second == nil || second.Status != JobQueued {
+			t.Fatalf("expected queued job, got %d %q %+v", code, decision, second)
+		}
+
+		_, _, third := click(mux)
+		if code := cancel(mux, third.ID); code != http.StatusAccepted {
+			t.Errorf("expected queued job to be canceled, got %d", code)
+		}
+		if j := wait(t, mux, third.ID); j.Status != JobCanceled {
+			t.Errorf("expected canceled job, got %s", j.Status)
+		}
+
+		close(release)
+		a, b := wait(t, mux, first.ID), wait(t, mux, second.ID)
+		if a.Status != JobSucceeded || b.Status != JobSucceeded {
+			t.Fatalf("expected both jobs to succeed, got %s and %s", a.Status, b.Status)
+		}
+		if b.Started.Before(*a.Ended) {
+			t.Errorf("expected queued job to start after the first ended, started %v, first ended %v", b.Started, *a.Ended)
+		}
+	})
+
+	t.Run("coalesce responds with the running job", func(t *testing.T) {
+		mux, release := setup(ConcurrencyCoalesce)
+
+		_, _, first := click(mux)
+		code, decision, second := click(mux)
+		if code != http.StatusAccepted || decision != "coalesced" || second == nil || second.ID != first.ID {
+			t.Errorf("expected the running job, got %d %q %+v", code, decision, second)
+		}
+
+		close(release)
+		wait(t, mux, first.ID)
+
+		if _, decision, third := click(mux); decision != "ran" || third.ID == first.ID {
+			t.Errorf("expected a new job once the first finished, got %q %+v", decision, third)
+		}
+	})
+}
+
+func TestValidateJobs(t *testing.T) {
+	job := func(context.Context, Progress) (ActionResult, error) { return ActionResult{}, nil }
+
+	m := &Menu{
+		Title: "Test",
+		Items: []Item{
+			{Title: "Both", Type: ItemTypeCallback, OnClick: "/both", Handler: noop, Job: job},
+			{Title: "Reserved", Type: ItemTypeCallback, OnClick: JobsPath + "/mine", Job: job},
+		},
+	}
+
+	err := m.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	for _, w := range []string{"items[0]: callback must not have both a handler and a job", `items[1]: callback path "/jobs/mine" is reserved`} {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("expected error to contain %q, got:\n%v", w, err)
+		}
+	}
+}
@@ -1,9 +1,17 @@
 package menu
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -11,12 +19,32 @@ const (
 	ItemTypeCallback ItemType = "callback"
 	// ItemTypeLink opens a link using default handler.
 	ItemTypeLink ItemType = "link"
+	// ItemTypeSeparator draws a horizontal line between groups of items.
+	// Clients must render it as a native separator and ignore every field other than type.
+	ItemTypeSeparator ItemType = "separator"
+	// ItemTypeHeader is a disabled, title-only item that labels the group of items below it.
+	// Clients must render it as non-clickable text (e.g. a section header) and never call back.
+	ItemTypeHeader ItemType = "header"
+	// ItemTypeToggle is an on/off (checkbox) item whose state is kept by the server.
+	// Clients call back to onClick with the requested state (e.g. "/dnd?checked=true").
+	ItemTypeToggle ItemType = "toggle"
+	// ItemTypeRadio is a group of mutually exclusive options (its Items) of which exactly one is selected.
+	// Clients call back to onClick with the chosen option value (e.g. "/env?value=prod").
+	ItemTypeRadio ItemType = "radio"
 )
 
 // ItemType represents the type of a menu item.
 // Dictates what happens on user click.
 type ItemType string
 
+// ToggleHandler is called when a toggle item is clicked with the requested new state.
+// Returning an error rejects the change and the item keeps its current state.
+type ToggleHandler func(ctx context.Context, checked bool) error
+
+// SelectHandler is called when an option of a radio group is chosen with the option value.
+// Returning an error rejects the change and the group keeps its current selection.
+type SelectHandler func(ctx context.Context, value string) error
+
 // Menu represents the root menu structure.
 type Menu struct {
 	// Title is the menu
@@ -28,13 +56,65 @@ type Menu struct {
 	// Version of the menu
 	Version string `json:"version,omitempty"`
 
-	// Items is the list of menu items
+	// Hash is the content hash of the served menu, also returned as its ETag.
+	// It is set by the server and changes whenever anything in the served menu changes.
+	Hash string `json:"hash,omitempty"`
+
+	// Items is the list of menu items.
+	// While the menu is served, change them with SetTitle, SetEnabled, Insert, Remove,
+	// Replace or Swap rather than directly.
 	Items []Item `json:"items,omitempty"`
+
+	// RefreshInterval is how often providers are called in the background by Run
+	// so that changes in their results are pushed to clients as events.
+	// Plugins are also called on their own interval, when it is shorter.
+	// If not specified, and there are no plugins, providers are only called when the menu is fetched.
+	RefreshInterval time.Duration `json:"-"`
+
+	// MaxJobs is how many jobs started by callback items can be queued or run at the same time.
+	// If not specified, DefaultMaxJobs is used.
+	MaxJobs int `json:"-"`
+
+	// state holds the server-side state of stateful items (e.g. toggles).
+	state state
+
+	// mu guards the tree (Title, Description, Version and Items) while it is served,
+	// so that it can be swapped atomically.
+	mu sync.RWMutex
+
+	// dynamic holds the items produced by providers.
+	dynamic dynamic
+
+	// routes serves the handlers of static and provided items.
+	routes atomic.Pointer[http.ServeMux]
+
+	// events notifies connected clients of menu changes.
+	events broker
+
+	// jobs holds the jobs started by callback items.
+	jobs jobs
+
+	// lanes holds the in-flight clicks of callback items with a concurrency policy.
+	lanes lanes
+
+	// usage records the clicks on items, see StatsHandler.
+	usage usage
 }
 
 // Item represents an individual item in the menu, which may contain sub-items.
 type Item struct {
-	// Type indicates the type of the menu item (e.g., callback, link).
+	// ID identifies the item in the menu, e.g. to find it (see Find) or to report its usage.
+	// If not specified, it is derived from the position of the item in the tree: the ID of its parent
+	// and a slug of its title (e.g. "item-2-submenu/subitem-1"), see Walk. IDs must be unique
+	// and are made of letters, digits, '.', '_' and '-' separated by '/'.
+	// The served JSON always carries the ID of every item.
+	//
+	// Derived IDs are kept when the menu is changed with SetTitle, Insert, Remove or Replace,
+	// but not when it is swapped or reloaded: set IDs explicitly for items whose title or
+	// position may change, or whose usage must be tracked across such changes.
+	ID string `json:"id"`
+
+	// Type indicates the type of the menu item (e.g., callback, link, separator, header).
 	Type ItemType `json:"type"`
 
 	// OnClick is the action to perform when the menu item is clicked.
@@ -42,69 +122,282 @@ type Item struct {
 	// For link type: URL to open (e.g., "https://github.com")
 	OnClick string `json:"onClick"`
 
+	// Method is the HTTP method clients must use to call back to OnClick, the only one accepted by the server.
+	// Applies to callback, toggle and radio items. If not specified, POST is used so that
+	// actions cannot be triggered by plain GET requests (e.g. an image on a web page).
+	// The served JSON always carries the method of items that call back.
+	Method string `json:"method,omitempty"`
+
 	// Handler is the HTTP handler associated with this menu item.
 	// This field is not serialized to JSON.
 	Handler http.Handler `json:"-"`
 
-	// Title is the title of the menu item.
+	// Job is the work done in the background when a callback item is clicked, instead of a Handler.
+	// The click responds immediately with the started job, see JobsHandler.
+	// This field is not serialized to JSON.
+	Job JobFunc `json:"-"`
+
+	// Concurrency is what happens to clicks on a callback item received while a previous
+	// click is still running or, for items with a Job, while the job it started is still running.
+	// If not specified, ConcurrencyAllow is used.
+	// This field is not serialized to JSON.
+	Concurrency Concurrency `json:"-"`
+
+	// Checked is the state of a toggle item or radio group option. When defining the menu
+	// it sets the initial state; the served JSON always reflects the current server-side state.
+	Checked bool `json:"checked,omitempty"`
+
+	// OnToggle is called when a toggle item is clicked.
+	// This field is not serialized to JSON.
+	OnToggle ToggleHandler `json:"-"`
+
+	// Value identifies an option of a radio group. Defaults to the option Title.
+	Value string `json:"value,omitempty"`
+
+	// OnSelect is called when an option of a radio group is chosen.
+	// This field is not serialized to JSON.
+	OnSelect SelectHandler `json:"-"`
+
+	// Title is the title of the menu item. Separators have no title.
 	Title string `json:"title"`
 
 	// Description is an optional description of the menu item.
 	Description string `json:"description,omitempty"`
 
-	// Shortcut is an optional keyboard shortcut for the menu item.
+	// Disabled items are shown to users but cannot be clicked; the server rejects their callbacks,
+	// and those of their sub-items, with 403. See SetEnabled.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Shortcut is an optional keyboard shortcut for the menu item (e.g., "cmd+shift+1").
+	// See ParseShortcut for the accepted format; it is served to clients in canonical form.
 	Shortcut string `json:"shortcut,omitempty"`
 
 	// Items are the sub-items of this menu item.
 	Items []Item `json:"items,omitempty"`
+
+	// Provider computes the sub-items of this item each time the menu is fetched.
+	// Items with a provider are submenus and must not define static Items.
+	// This field is not serialized to JSON.
+	Provider Provider `json:"-"`
+
+	// ProviderTimeout is the maximum duration the Provider has to compute the sub-items.
+	// If not specified, the timeout of the provider (e.g. Plugin.Timeout) or DefaultProviderTimeout (2s) is used.
+	// This field is not serialized to JSON.
+	ProviderTimeout time.Duration `json:"-"`
+}
+
+// MarshalJSON serializes the item with its shortcut normalized to the canonical form.
+// Shortcuts that fail to parse are left as-is; Validate reports them.
+func (i Item) MarshalJSON() ([]byte, error) {
+	type item Item // prevents recursion into this method
+
+	out := item(i)
+	if sc, err := ParseShortcut(i.Shortcut); err == nil {
+		out.Shortcut = sc.String()
+	}
+
+	return json.Marshal(out)
+}
+
+// callsBack reports whether clicking the item sends a request to the server at OnClick.
+func (i *Item) callsBack() bool {
+	return i.Type == ItemTypeCallback || i.Type == ItemTypeToggle || i.Type == ItemTypeRadio
+}
+
+// method returns the HTTP method used to call back to the item, POST by default.
+func (i *Item) method() string {
+	if i.Method == "" {
+		return http.MethodPost
+	}
+
+	return strings.ToUpper(i.Method)
 }
 
 // ToJSON returns a JSON-serializable representation of the menu.
-// The Handler field is excluded from serialization.
+// The Handler field is excluded from serialization, stateful items
+// (e.g. toggles) reflect their current server-side state and items with
+// a provider contain the last good result of that provider.
 func (m *Menu) ToJSON() interface{} {
-	return m
+	return m.view()
+}
+
+// view returns a copy of the menu as it is served to clients.
+func (m *Menu) view() *Menu {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return &Menu{
+		Title:       m.Title,
+		Description: m.Description,
+		Version:     m.Version,
+		Items:       m.snapshot("items", "", m.Items),
+	}
+}
+
+// snapshot returns a copy of the items located under prefix, below the parent ID,
+// with their IDs set and provider results and the current server-side state applied.
+func (m *Menu) snapshot(prefix, parent string, items []Item) []Item {
+	if items == nil {
+		return nil
+	}
+
+	ids := itemIDs(parent, items)
+	out := make([]Item, len(items))
+	for i, item := range items {
+		at := fmt.Sprintf("%s[%d]", prefix, i)
+		item.ID = ids[i]
+
+		if item.Provider != nil {
+			item.Items = m.provided(at)
+		}
+		item.Items = m.snapshot(at+".items", item.ID, item.Items)
+
+		if item.callsBack() {
+			item.Method = item.method()
+		}
+
+		switch item.Type {
+		case ItemTypeToggle:
+			item.Checked = m.state.checked(item.OnClick, item.Checked)
+		case ItemTypeRadio:
+			selected := m.state.selected(item.OnClick, item.initialValue())
+			for j := range item.Items {
+				item.Items[j].Value = item.Items[j].value()
+				item.Items[j].Checked = item.Items[j].Value == selected
+			}
+		}
+
+		out[i] = item
+	}
+
+	return out
 }
 
 // RegisterHandlers walks through the menu tree and registers all handlers with the server.
-// It recursively processes all menu items and their sub-items.
+// It recursively processes all menu items and their sub-items. Handlers are registered
+// with a method pattern (e.g. "POST /item1"), so that the server answers other methods
+// with 405, callback handlers are wrapped to enforce the concurrency policy of their item,
+// and every handler is wrapped to record the clicks on its item (see StatsHandler).
 func (m *Menu) RegisterHandlers(register func(pattern string, handler http.Handler)) {
-	for i := range m.Items {
-		m.registerItem(&m.Items[i], register)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i, id := range itemIDs("", m.Items) {
+		m.registerItem(id, &m.Items[i], register)
 	}
 }
 
-// registerItem recursively registers a menu item and all its sub-items.
-func (m *Menu) registerItem(item *Item, register func(pattern string, handler http.Handler)) {
-	if item.Handler != nil {
-		register(item.OnClick, item.Handler)
+// registerItem recursively registers a menu item with the given ID and all its sub-items.
+func (m *Menu) registerItem(id string, item *Item, register func(pattern string, handler http.Handler)) {
+	pattern := item.method() + " " + item.OnClick
+
+	switch {
+	case item.Disabled:
+		registerDisabled(item, register)
+		return
+	case item.Type == ItemTypeToggle:
+		register(pattern, m.count(id, item, m.toggleHandler(item)))
+	case item.Type == ItemTypeRadio:
+		register(pattern, m.count(id, item, m.radioHandler(item)))
+		return
+	case item.Job != nil:
+		register(pattern, m.count(id, item, m.jobHandler(item)))
+	case item.Handler != nil:
+		register(pattern, m.count(id, item, m.guard(item, item.Handler)))
 	}
 
-	for i := range item.Items {
-		m.registerItem(&item.Items[i], register)
+	for i, sub := range itemIDs(id, item.Items) {
+		m.registerItem(sub, &item.Items[i], register)
 	}
 }
 
 // Handler returns an HTTP handler that responds with the menu structure as JSON.
+// Providers are called on every GET request to the root path; other methods are answered
+// with 405. Requests to any other path are routed to the handlers of the menu items,
+// including those produced by providers, or answered with 404. Routes follow the served
+// tree when it is swapped.
 func (m *Menu) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			m.serveRoutes(w, r)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
 		slog.Info("handling menu request",
 			"method", r.Method,
 			"url", r.URL.Path,
 		)
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
+		m.resolve(r.Context())
 
-		if err := json.NewEncoder(w).Encode(m.ToJSON()); err != nil {
+		b, hash, err := m.encode()
+		if err != nil {
 			slog.Error("failed to encode menu", "error", err)
 			http.Error(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 
+		etag := `"` + hash + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+
+		status := http.StatusOK
+		if matchETag(r.Header.Get("If-None-Match"), etag) {
+			status = http.StatusNotModified
+			w.WriteHeader(status)
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			if _, err := w.Write(b); err != nil {
+				slog.Error("failed to write menu", "error", err)
+				return
+			}
+		}
+
 		slog.Info("menu response sent",
 			"method", r.Method,
 			"url", r.URL.Path,
-			"status", http.StatusOK,
+			"status", status,
 		)
 	})
 }
+
+// encode serializes the menu as it is served to clients along with its content hash.
+// The hash covers everything in the served menu except the hash itself, so it only
+// changes when the content does.
+func (m *Menu) encode() ([]byte, string, error) {
+	v := m.view()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal menu: %w", err)
+	}
+
+	sum := sha256.Sum256(b)
+	v.Hash = hex.EncodeToString(sum[:16])
+
+	if b, err = json.Marshal(v); err != nil {
+		return nil, "", fmt.Errorf("failed to marshal menu: %w", err)
+	}
+
+	return append(b, '\n'), v.Hash, nil
+}
+
+// matchETag reports whether the If-None-Match header value matches the ETag.
+// Uses the weak comparison required for conditional GET requests (RFC 9110).
+func matchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
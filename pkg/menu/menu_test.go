@@ -0,0 +1,177 @@
+package menu
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serve registers the menu and its item handlers on a new mux.
+func serve(m *Menu) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/", m.Handler())
+	m.RegisterHandlers(mux.Handle)
+
+	return mux
+}
+
+// get decodes the JSON served at path into v.
+func get(t *testing.T, h http.Handler, path string, v any) int {
+	t.Helper()
+	return request(t, h, http.MethodGet, path, v)
+}
+
+// post clicks the item at path, the way clients call back by default, and decodes the response into v.
+func post(t *testing.T, h http.Handler, path string, v any) int {
+	t.Helper()
+	return request(t, h, http.MethodPost, path, v)
+}
+
+// request sends a request with the given method to path and decodes the JSON response into v.
+func request(t *testing.T, h http.Handler, method, path string, v any) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("failed to decode %s response %q: %v", path, rec.Body.String(), err)
+		}
+	}
+
+	return rec.Code
+}
+
+func TestHandlerETag(t *testing.T) {
+	m := &Menu{
+		Title: "Test",
+		Items: []Item{
+			{Title: "DND", Type: ItemTypeToggle, OnClick: "/dnd", OnToggle: func(context.Context, bool) error { return nil }},
+		},
+	}
+	mux := serve(m)
+
+	fetch := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := fetch("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with ETag, got %d %q", first.Code, etag)
+	}
+
+	var served Menu
+	get(t, mux, "/", &served)
+	if `"`+served.Hash+`"` != etag {
+		t.Errorf("expected body hash %q to match ETag %q", served.Hash, etag)
+	}
+
+	if again := fetch(""); again.Header().Get("ETag") != etag {
+		t.Errorf("expected stable ETag %q, got %q", etag, again.Header().Get("ETag"))
+	}
+
+	for _, h := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		if rec := fetch(h); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: expected empty 304, got %d with %d bytes", h, rec.Code, rec.Body.Len())
+		}
+	}
+
+	post(t, mux, "/dnd", nil)
+
+	changed := fetch(etag)
+	if changed.Code != http.StatusOK {
+		t.Errorf("expected 200 after change, got %d", changed.Code)
+	}
+	if changed.Header().Get("ETag") == etag {
+		t.Error("expected ETag to change with the menu state")
+	}
+}
+
+func TestMethods(t *testing.T) {
+	m := &Menu{
+		Title: "Test",
+		Items: []Item{
+			{Title: "Deploy", Type: ItemTypeCallback, OnClick: "/deploy", Handler: noop},
+			{Title: "Status", Type: ItemTypeCallback, OnClick: "/status", Method: "get", Handler: noop},
+			{Title: "GitHub", Type: ItemTypeLink, OnClick: "https://github.com"},
+		},
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("expected valid menu, got: %v", err)
+	}
+	mux := serve(m)
+
+	t.Run("callbacks accept only their method", func(t *testing.T) {
+		if code := post(t, mux, "/deploy", nil); code != http.StatusOK {
+			t.Errorf("expected 200 for POST, got %d", code)
+		}
+		if code := get(t, mux, "/status", nil); code != http.StatusOK {
+			t.Errorf("expected 200 for declared GET, got %d", code)
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/deploy", nil))
+		if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != http.MethodPost {
+			t.Errorf("expected 405 with Allow: POST, got %d %q", rec.Code, rec.Header().Get("Allow"))
+		}
+	})
+
+	t.Run("menu is only served to GET and HEAD", func(t *testing.T) {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(method, "/", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("%s: expected 200, got %d", method, rec.Code)
+			}
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+		if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+			t.Errorf("expected 405 with Allow: GET, HEAD, got %d %q", rec.Code, rec.Header().Get("Allow"))
+		}
+	})
+
+	t.Run("method is served for items that call back", func(t *testing.T) {
+		var served struct {
+			Items []struct {
+				Method string `json:"method"`
+			} `json:"items"`
+		}
+		get(t, mux, "/", &served)
+
+		for i, want := range []string{http.MethodPost, http.MethodGet, ""} {
+			if got := served.Items[i].Method; got != want {
+				t.Errorf("items[%d]: expected method %q, got %q", i, want, got)
+			}
+		}
+	})
+
+	t.Run("method is validated", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "A", Type: ItemTypeCallback, OnClick: "/a", Method: "FETCH", Handler: noop},
+				{Title: "B", Type: ItemTypeLink, OnClick: "https://github.com", Method: http.MethodGet},
+			},
+		}
+
+		err := m.Validate()
+		for _, w := range []string{`items[0]: unsupported method "FETCH"`, "items[1]: only callback, toggle and radio items can have a method"} {
+			if err == nil || !strings.Contains(err.Error(), w) {
+				t.Errorf("expected error to contain %q, got:\n%v", w, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+// ErrItemNotFound is returned when changing an item that is not in the menu.
+// Only the items defined in the menu can be changed, not those produced by providers.
+var ErrItemNotFound = errors.New("item not found")
+
+// SetTitle changes the title of the item with the ID. An item without an explicit ID keeps
+// the one it had, derived from its previous title, so that it can still be found by it.
+func (m *Menu) SetTitle(id, title string) error {
+	return m.mutate("retitled", id, false, func(items []Item) ([]Item, error) {
+		return update("", items, id, func(siblings []Item, i int) []Item {
+			siblings[i].Title = title
+			return siblings
+		})
+	})
+}
+
+// SetEnabled enables or disables the item with the ID. Clients render disabled items
+// without letting them be clicked and the server rejects their callbacks, and those of
+// their sub-items, with 403.
+func (m *Menu) SetEnabled(id string, enabled bool) error {
+	kind := "enabled"
+	if !enabled {
+		kind = "disabled"
+	}
+
+	return m.mutate(kind, id, false, func(items []Item) ([]Item, error) {
+		return update("", items, id, func(siblings []Item, i int) []Item {
+			siblings[i].Disabled = !enabled
+			return siblings
+		})
+	})
+}
+
+// Insert adds the item to the sub-items of the item with the parent ID, at the given index
+// (len to append), or to the top level of the menu when the parent ID is empty.
+// An item without an explicit ID gets the one it would have if appended, so that inserting it
+// does not change the IDs of its new siblings. The handlers of the item and its sub-items
+// are served right away.
+func (m *Menu) Insert(parent string, index int, item Item) error {
+	return m.mutate("inserted", item.Title, true, func(items []Item) ([]Item, error) {
+		if parent == "" {
+			return insertAt("", items, index, item)
+		}
+
+		var inserted error
+		out, err := update("", items, parent, func(siblings []Item, i int) []Item {
+			siblings[i].Items, inserted = insertAt(siblings[i].ID, siblings[i].Items, index, item)
+			return siblings
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return out, inserted
+	})
+}
+
+// insertAt returns a copy of the items under the parent ID, with their IDs pinned,
+// and the item inserted at the index.
+func insertAt(parent string, items []Item, index int, item Item) ([]Item, error) {
+	if index < 0 || index > len(items) {
+		return nil, fmt.Errorf("index %d out of range [0, %d]", index, len(items))
+	}
+
+	if item.ID == "" {
+		ids := itemIDs(parent, append(slices.Clone(items), item))
+		item.ID = ids[len(ids)-1]
+	}
+
+	return slices.Insert(pin(parent, items), index, item), nil
+}
+
+// Remove removes the item with the ID, along with its sub-items, from the menu.
+// Their handlers are no longer served.
+func (m *Menu) Remove(id string) error {
+	return m.mutate("removed", id, true, func(items []Item) ([]Item, error) {
+		return update("", items, id, func(siblings []Item, i int) []Item {
+			return slices.Delete(siblings, i, i+1)
+		})
+	})
+}
+
+// Replace replaces the item with the ID, along with its sub-items, with the given item.
+// The item keeps its place in the menu and, unless the new item sets its own, its ID;
+// the handlers of the new item replace those of the old one.
+func (m *Menu) Replace(id string, item Item) error {
+	return m.mutate("replaced", id, true, func(items []Item) ([]Item, error) {
+		return update("", items, id, func(siblings []Item, i int) []Item {
+			if item.ID == "" {
+				item.ID = id
+			}
+			siblings[i] = item
+			return siblings
+		})
+	})
+}
+
+// mutate applies the change to a copy of the items of the tree, validates the result and swaps it in
+// along with the routes of its items, all while holding the tree lock so that the served menu is
+// always consistent. When positions in the tree change (reset), provider results are discarded and
+// computed again on the next fetch, as for Swap. Clients are notified of the change.
+func (m *Menu) mutate(kind, item string, reset bool, change func(items []Item) ([]Item, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items, err := change(m.Items)
+	if err != nil {
+		return err
+	}
+
+	next := &Menu{Title: m.Title, Items: items}
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("invalid menu: %w", err)
+	}
+
+	m.Items = items
+
+	m.dynamic.mu.Lock()
+	if reset {
+		m.dynamic.generation++
+		m.dynamic.results = nil
+	}
+	m.route()
+	m.dynamic.mu.Unlock()
+
+	slog.Info("menu item "+kind, "item", item)
+
+	m.Publish()
+
+	return nil
+}
+
+// update returns a copy of the items in which the list of siblings holding the item with the ID
+// is replaced by the result of fn, called with a copy of that list, with their IDs pinned,
+// and the index of the item. Only the lists on the way to the item are copied, so the tree
+// being served is never modified.
+func update(parent string, items []Item, id string, fn func(siblings []Item, i int) []Item) ([]Item, error) {
+	for i, iid := range itemIDs(parent, items) {
+		if iid == id {
+			return fn(pin(parent, items), i), nil
+		}
+
+		if sub, err := update(iid, items[i].Items, id, fn); err == nil {
+			out := slices.Clone(items)
+			out[i].Items = sub
+			return out, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrItemNotFound, id)
+}
+
+// pin returns a copy of the items under the parent ID with their derived IDs made explicit.
+// The IDs of the siblings of a changed item are pinned, since derived IDs depend on the titles
+// and the order of the siblings: a sibling titled like the item would otherwise get another ID
+// when it is inserted, removed, replaced or retitled, and its usage would move to another item.
+// The sub-items of pinned items keep their IDs since their parent ID does not change.
+func pin(parent string, items []Item) []Item {
+	out := slices.Clone(items)
+	for i, id := range itemIDs(parent, items) {
+		out[i].ID = id
+	}
+
+	return out
+}
+
+// registerDisabled registers a handler rejecting the clicks on a disabled item and on its sub-items.
+func registerDisabled(item *Item, register func(pattern string, handler http.Handler)) {
+	if item.callsBack() {
+		register(item.method()+" "+item.OnClick, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			WriteActionError(w, http.StatusForbidden, errors.New("item is disabled"))
+		}))
+	}
+
+	if item.Type == ItemTypeRadio {
+		return
+	}
+
+	for i := range item.Items {
+		registerDisabled(&item.Items[i], register)
+	}
+}
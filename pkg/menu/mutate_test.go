@@ -0,0 +1,260 @@
+package menu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMutate(t *testing.T) {
+	// Items are routed by the menu handler, as in Run, so that routes follow the changes
+	newMenu := func() (*Menu, http.Handler) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "Hello", Type: ItemTypeCallback, OnClick: "/hello", Handler: noop},
+				{Title: "Tools", Items: []Item{
+					{Title: "Build", Type: ItemTypeCallback, OnClick: "/build", Handler: noop},
+				}},
+			},
+		}
+		return m, m.Handler()
+	}
+
+	t.Run("set title keeps the id", func(t *testing.T) {
+		m, h := newMenu()
+
+		if err := m.SetTitle("tools/build", "Build (running)"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var served Menu
+		get(t, h, "/", &served)
+
+		if item := served.Items[1].Items[0]; item.Title != "Build (running)" || item.ID != "tools/build" {
+			t.Errorf("unexpected item: %+v", item)
+		}
+	})
+
+	t.Run("disabled items and their sub-items reject clicks", func(t *testing.T) {
+		m, h := newMenu()
+
+		if err := m.SetEnabled("tools", false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := m.SetEnabled("hello", false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var served Menu
+		get(t, h, "/", &served)
+		if !served.Items[0].Disabled || !served.Items[1].Disabled {
+			t.Errorf("expected items to be served disabled, got %+v", served.Items)
+		}
+
+		var res ActionResult
+		if code := post(t, h, "/hello", &res); code != http.StatusForbidden || res.Error == "" {
+			t.Errorf("expected 403 with an error, got %d %+v", code, res)
+		}
+		if code := post(t, h, "/build", nil); code != http.StatusForbidden {
+			t.Errorf("expected sub-item to be disabled, got %d", code)
+		}
+
+		if err := m.SetEnabled("hello", true); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if code := post(t, h, "/hello", nil); code != http.StatusOK {
+			t.Errorf("expected enabled item to be clicked, got %d", code)
+		}
+	})
+
+	t.Run("items of disabled providers reject clicks", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "Live", Provider: ProviderFunc(func(context.Context) ([]Item, error) {
+					return []Item{{Title: "Dynamic", Type: ItemTypeCallback, OnClick: "/dyn", Handler: noop}}, nil
+				})},
+				{Title: "Tools", Items: []Item{
+					{Title: "Nested", Provider: ProviderFunc(func(context.Context) ([]Item, error) {
+						return []Item{{Title: "Deep", Type: ItemTypeCallback, OnClick: "/deep", Handler: noop}}, nil
+					})},
+				}},
+			},
+		}
+		h := m.Handler()
+
+		var served Menu
+		get(t, h, "/", &served)
+		for _, path := range []string{"/dyn", "/deep"} {
+			if code := post(t, h, path, nil); code != http.StatusOK {
+				t.Fatalf("%s: expected status %d, got %d", path, http.StatusOK, code)
+			}
+		}
+
+		if err := m.SetEnabled("live", false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := m.SetEnabled("tools", false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, path := range []string{"/dyn", "/deep"} {
+			if code := post(t, h, path, nil); code != http.StatusForbidden {
+				t.Errorf("%s: expected status %d, got %d", path, http.StatusForbidden, code)
+			}
+		}
+
+		// Fetching the menu computes the results again, still under disabled items
+		get(t, h, "/", &served)
+		if code := post(t, h, "/dyn", nil); code != http.StatusForbidden {
+			t.Errorf("expected status %d after a fetch, got %d", http.StatusForbidden, code)
+		}
+	})
+
+	t.Run("inserted items are routed", func(t *testing.T) {
+		m, h := newMenu()
+
+		if err := m.Insert("", 0, Item{Title: "First", Type: ItemTypeCallback, OnClick: "/first", Handler: noop}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := m.Insert("tools", 1, Item{Title: "Test", Type: ItemTypeCallback, OnClick: "/test", Handler: noop}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var served Menu
+		get(t, h, "/", &served)
+		if served.Items[0].ID != "first" || served.Items[2].Items[1].ID != "tools/test" {
+			t.Errorf("unexpected items: %+v", served.Items)
+		}
+
+		for _, path := range []string{"/first", "/test"} {
+			if code := post(t, h, path, nil); code != http.StatusOK {
+				t.Errorf("%s: expected status %d, got %d", path, http.StatusOK, code)
+			}
+		}
+	})
+
+	t.Run("ids of existing items are kept", func(t *testing.T) {
+		m, h := newMenu()
+
+		// The new item is titled like an existing one and inserted before it
+		if err := m.Insert("", 0, Item{Title: "Hello", Type: ItemTypeCallback, OnClick: "/hello-again", Handler: noop}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if item, ok := m.Find("hello"); !ok || item.OnClick != "/hello" {
+			t.Errorf("expected existing item to keep its id, got %+v", item)
+		}
+		if item, ok := m.Find("hello-2"); !ok || item.OnClick != "/hello-again" {
+			t.Errorf("expected inserted item to get a new id, got %+v", item)
+		}
+
+		post(t, h, "/hello", nil)
+
+		if err := m.Remove("hello"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if item, ok := m.Find("hello-2"); !ok || item.OnClick != "/hello-again" {
+			t.Errorf("expected remaining item to keep its id, got %+v", item)
+		}
+
+		if err := m.Replace("hello-2", Item{Title: "Hi", Type: ItemTypeCallback, OnClick: "/hi", Handler: noop}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if item, ok := m.Find("hello-2"); !ok || item.OnClick != "/hi" {
+			t.Errorf("expected replacement to take over the id, got %+v", item)
+		}
+
+		var s Stats
+		get(t, m.StatsHandler(), StatsPath, &s)
+		if len(s.Items) == 0 || s.Items[0].ID != "hello" || s.Items[0].Clicks != 1 || s.Items[1].Clicks != 0 {
+			t.Errorf("expected clicks to stay with the clicked item, got %+v", s.Items)
+		}
+	})
+
+	t.Run("removed items are no longer routed", func(t *testing.T) {
+		m, h := newMenu()
+
+		if err := m.Remove("tools"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, ok := m.Find("tools/build"); ok {
+			t.Error("expected sub-item to be removed")
+		}
+		if code := post(t, h, "/build", nil); code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, code)
+		}
+	})
+
+	t.Run("replaced items get the new handler", func(t *testing.T) {
+		m, h := newMenu()
+
+		accepted := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+		if err := m.Replace("hello", Item{ID: "hello", Title: "Hi", Type: ItemTypeCallback, OnClick: "/hello", Handler: accepted}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if item, ok := m.Find("hello"); !ok || item.Title != "Hi" {
+			t.Errorf("expected replaced item, got %+v", item)
+		}
+		if code := post(t, h, "/hello", nil); code != http.StatusAccepted {
+			t.Errorf("expected status %d, got %d", http.StatusAccepted, code)
+		}
+	})
+
+	t.Run("invalid changes are rejected", func(t *testing.T) {
+		m, _ := newMenu()
+
+		if err := m.SetTitle("missing", "Title"); !errors.Is(err, ErrItemNotFound) {
+			t.Errorf("expected ErrItemNotFound, got %v", err)
+		}
+		if err := m.Insert("missing", 0, Item{Title: "X", Type: ItemTypeHeader}); !errors.Is(err, ErrItemNotFound) {
+			t.Errorf("expected ErrItemNotFound, got %v", err)
+		}
+		if err := m.Insert("tools", 5, Item{Title: "X", Type: ItemTypeHeader}); err == nil || !strings.Contains(err.Error(), "out of range") {
+			t.Errorf("expected out of range error, got %v", err)
+		}
+
+		err := m.Insert("", 0, Item{Title: "Dup", Type: ItemTypeCallback, OnClick: "/hello", Handler: noop})
+		if err == nil || !strings.Contains(err.Error(), `duplicate callback path "/hello"`) {
+			t.Errorf("expected duplicate path error, got %v", err)
+		}
+		if len(m.Items) != 2 {
+			t.Errorf("expected menu to be unchanged, got %d items", len(m.Items))
+		}
+	})
+
+	t.Run("changes are safe while the menu is served", func(t *testing.T) {
+		m, h := newMenu()
+
+		var wg sync.WaitGroup
+		for i := range 10 {
+			wg.Go(func() {
+				path := fmt.Sprintf("/item%d", i)
+				if err := m.Insert("tools", 0, Item{Title: path, Type: ItemTypeCallback, OnClick: path, Handler: noop}); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if err := m.SetTitle("hello", path); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+			wg.Go(func() {
+				var served Menu
+				get(t, h, "/", &served)
+				post(t, h, "/build", nil)
+			})
+		}
+		wg.Wait()
+
+		if item, ok := m.Find("tools"); !ok || len(item.Items) != 11 {
+			t.Errorf("expected every item to be inserted, got %+v", item)
+		}
+	})
+}
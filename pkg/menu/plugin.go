@@ -0,0 +1,402 @@
+package menu
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPluginInterval is how often a plugin is run when its file name does not set a refresh interval.
+	DefaultPluginInterval = time.Minute
+
+	// PluginPathPrefix is the server path prefix of the callback items produced by plugins.
+	PluginPathPrefix = "/plugins"
+
+	// pluginSeparator separates the menu bar lines from the dropdown lines
+	// and, in the dropdown, draws a separator.
+	pluginSeparator = "---"
+
+	// pluginLevel prefixes dropdown lines once per submenu level.
+	pluginLevel = "--"
+)
+
+// Plugin runs an xbar/SwiftBar plugin and provides its output as menu items, so that existing
+// plugin scripts can be reused unchanged. Attach it as the Provider of a submenu item.
+//
+// The plugin is run once per refresh interval, taken from its file name (e.g. "cpu.10s.sh"
+// runs every 10 seconds): Run calls it in the background on that interval and pushes changes
+// in its output to clients. The output of the last run is provided in between.
+//
+// Output lines are mapped as follows:
+//   - lines before the first "---" (menu bar lines) become header items
+//   - "---" becomes a separator, "--" prefixes nest lines in submenus
+//   - "| href=URL" becomes a link item
+//   - "| bash=PATH param1=.. paramN=.." becomes a callback item running the command
+//     (served under PluginPathPrefix, see line)
+//   - "| refresh=true" makes the plugin run again after the item is clicked
+//   - "| tooltip=TEXT" sets the description, "| key=cmd+k" (SwiftBar) sets the shortcut
+//   - "| dropdown=false" and "| alternate=true" lines are skipped
+//   - any other line becomes a header item (disabled text)
+type Plugin struct {
+	// Path is the plugin executable.
+	Path string
+
+	// Interval is how often the plugin is run. Defaults to the interval in the file name,
+	// or DefaultPluginInterval.
+	Interval time.Duration
+
+	// Timeout is the maximum duration a run of the plugin or of one of its commands may take.
+	// It is also the provider timeout of the item, unless the item sets its ProviderTimeout.
+	// If not specified, DefaultCommandTimeout is used.
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	items []Item
+	ran   time.Time
+}
+
+// NewPlugin returns a plugin running the executable at path on the refresh interval in its file name.
+func NewPlugin(path string) *Plugin {
+	return &Plugin{
+		Path:     path,
+		Interval: PluginInterval(path),
+	}
+}
+
+// PluginInterval parses the refresh interval from a plugin file name in the
+// "{name}.{time}.{ext}" format, where time is a number followed by s, m, h or d
+// (e.g. "cpu.10s.sh"). Returns DefaultPluginInterval if the name has no valid interval.
+func PluginInterval(path string) time.Duration {
+	parts := strings.Split(filepath.Base(path), ".")
+	if len(parts) < 3 {
+		return DefaultPluginInterval
+	}
+
+	spec := parts[len(parts)-2]
+	if len(spec) < 2 {
+		return DefaultPluginInterval
+	}
+
+	n, err := strconv.Atoi(spec[:len(spec)-1])
+	if err != nil || n <= 0 {
+		return DefaultPluginInterval
+	}
+
+	unit := map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour}[spec[len(spec)-1]]
+	if unit == 0 {
+		return DefaultPluginInterval
+	}
+
+	return time.Duration(n) * unit
+}
+
+// name returns the plugin name, the first part of its file name.
+func (p *Plugin) name() string {
+	base := filepath.Base(p.Path)
+	if i := strings.Index(base, "."); i > 0 {
+		return base[:i]
+	}
+
+	return base
+}
+
+// Items runs the plugin if its refresh interval has elapsed and returns the items parsed from its output.
+// Implements Provider.
+func (p *Plugin) Items(ctx context.Context) ([]Item, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultPluginInterval
+	}
+
+	if p.items != nil && time.Since(p.ran) < interval {
+		return p.items, nil
+	}
+
+	// Measured from the start of the run, so that the background refresh, which waits for
+	// the interval once the run has finished, finds the plugin due again
+	started := time.Now()
+	res := Command{Name: p.Path, Timeout: p.Timeout}.Run(ctx)
+	if res.Error != "" {
+		return nil, fmt.Errorf("plugin %s failed: %s: %s", p.Path, res.Error, strings.TrimSpace(res.Stderr))
+	}
+
+	items, err := p.parse(res.Stdout)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.Path, err)
+	}
+
+	p.items, p.ran = items, started
+	slog.Debug("plugin refreshed", "path", p.Path, "items", len(items))
+
+	return items, nil
+}
+
+// timeout returns the maximum duration a run of the plugin may take.
+// Implements timeoutProvider, so that the plugin is not canceled after DefaultProviderTimeout.
+func (p *Plugin) timeout() time.Duration {
+	if p.Timeout <= 0 {
+		return DefaultCommandTimeout
+	}
+
+	return p.Timeout
+}
+
+// interval returns how often the plugin is run.
+// Implements intervalProvider, so that Run refreshes the plugin in the background on that interval.
+func (p *Plugin) interval() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Interval <= 0 {
+		return DefaultPluginInterval
+	}
+
+	return p.Interval
+}
+
+// invalidate makes the plugin run again on the next call to Items.
+func (p *Plugin) invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ran = time.Time{}
+}
+
+// parse converts plugin output into menu items.
+func (p *Plugin) parse(out string) ([]Item, error) {
+	var (
+		root     []Item
+		stack    = []*[]Item{&root} // items of the current submenu at each level
+		titles   []string           // titles of the submenus on the way to the current level
+		dropdown bool
+		actions  = map[string]int{} // callback paths already used by the output
+	)
+
+	sc := bufio.NewScanner(strings.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64*1024), MaxCommandOutput)
+
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if !dropdown {
+			if line == pluginSeparator {
+				dropdown = true
+				continue
+			}
+			if item, ok := p.line(line, nil, actions); ok {
+				item.Type, item.OnClick, item.Handler, item.Shortcut = ItemTypeHeader, "", nil, ""
+				root = append(root, item)
+			}
+			continue
+		}
+
+		// Separators are made of dashes too: "-----" is a separator in a submenu
+		level := 0
+		for strings.HasPrefix(line, pluginLevel) &&
+			(strings.Trim(line, "-") != "" || len(line)-len(pluginLevel) >= len(pluginSeparator)) {
+			line = strings.TrimPrefix(line, pluginLevel)
+			level++
+		}
+
+		if level >= len(stack) {
+			level = len(stack) - 1 // deeper than the previous line: attach to the deepest submenu
+		}
+		stack, titles = stack[:level+1], titles[:level]
+		parent := stack[level]
+
+		if isPluginSeparator(line) {
+			*parent = append(*parent, Item{Type: ItemTypeSeparator})
+			continue
+		}
+
+		item, ok := p.line(line, titles, actions)
+		if !ok {
+			continue
+		}
+
+		*parent = append(*parent, item)
+		last := &(*parent)[len(*parent)-1]
+		stack, titles = append(stack, &last.Items), append(titles, item.Title)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read output: %w", err)
+	}
+
+	submenus(root)
+
+	return root, nil
+}
+
+// isPluginSeparator reports whether the line, stripped of its level prefix, is a separator.
+func isPluginSeparator(line string) bool {
+	return line == pluginSeparator || (strings.Trim(line, "-") == "" && len(line) >= len(pluginSeparator))
+}
+
+// submenus turns items that received nested lines into plain submenus,
+// since items with sub-items cannot also be clickable.
+func submenus(items []Item) {
+	for i := range items {
+		if len(items[i].Items) == 0 {
+			items[i].Items = nil
+			continue
+		}
+
+		items[i].Type, items[i].OnClick, items[i].Handler, items[i].Shortcut = "", "", nil, ""
+		submenus(items[i].Items)
+	}
+}
+
+// line converts a single plugin line, nested under the submenus with the given titles, into an item.
+// Returns false if the line must be skipped.
+//
+// The callback path of an action is derived from the plugin file and from the action itself:
+// the titles on the way to the item, the command it runs and whether it refreshes the plugin.
+// A click from a menu fetched before the output of the plugin changed thus runs the command
+// it showed or, if that action is gone, is answered with 404. Identical actions get a -2, -3..
+// suffix, actions records the paths already used.
+func (p *Plugin) line(line string, titles []string, actions map[string]int) (Item, bool) {
+	title, rest, _ := strings.Cut(line, "|")
+	params := pluginParams(rest)
+
+	if params["dropdown"] == "false" || params["alternate"] == "true" {
+		return Item{}, false
+	}
+
+	item := Item{
+		Title:       strings.TrimSpace(title),
+		Description: params["tooltip"],
+		Type:        ItemTypeHeader,
+	}
+	if item.Title == "" {
+		return Item{}, false
+	}
+
+	refresh := params["refresh"] == "true"
+
+	switch {
+	case params["href"] != "":
+		item.Type = ItemTypeLink
+		item.OnClick = params["href"]
+	case params["bash"] != "" || params["shell"] != "" || refresh:
+		cmd := p.command(params)
+		item.Type = ItemTypeCallback
+		item.OnClick = p.actionPath(append(slices.Clone(titles), item.Title), cmd, refresh, actions)
+		item.Handler = p.action(cmd, refresh)
+	}
+
+	if key := params["key"]; key != "" && item.Type != ItemTypeHeader {
+		if sc, err := ParseShortcut(pluginShortcut(key)); err == nil {
+			item.Shortcut = sc.String()
+		}
+	}
+
+	return item, true
+}
+
+// actionPath returns the callback path of an action, "/plugins/{name}-{plugin}/{action}",
+// where plugin is a hash of the plugin path, so that plugins with the same name do not share paths,
+// and action a hash of the titles of the item, the command and whether it refreshes the plugin.
+func (p *Plugin) actionPath(titles []string, cmd Command, refresh bool, actions map[string]int) string {
+	plugin := sha256.Sum256([]byte(filepath.Clean(p.Path)))
+	action := sha256.Sum256(fmt.Appendf(nil, "%q %q %q %t", titles, cmd.Name, cmd.Args, refresh))
+
+	path := fmt.Sprintf("%s/%s-%s/%s", PluginPathPrefix, p.name(), hex.EncodeToString(plugin[:4]), hex.EncodeToString(action[:8]))
+
+	actions[path]++
+	if n := actions[path]; n > 1 {
+		path = fmt.Sprintf("%s-%d", path, n)
+	}
+
+	return path
+}
+
+// command returns the bash command of a plugin line with its parameters, if any.
+func (p *Plugin) command(params map[string]string) Command {
+	name := params["bash"]
+	if name == "" {
+		name = params["shell"]
+	}
+
+	var args []string
+	for i := 1; ; i++ {
+		v, ok := params[fmt.Sprintf("param%d", i)]
+		if !ok {
+			break
+		}
+		args = append(args, v)
+	}
+
+	return Command{Name: name, Args: args, Timeout: p.Timeout}
+}
+
+// action returns the handler of a plugin callback item: it runs the bash command, if any,
+// and when refresh is set makes the plugin run again and asks the client to fetch the menu.
+func (p *Plugin) action(cmd Command, refresh bool) http.Handler {
+	return ActionFunc(func(r *http.Request) (ActionResult, error) {
+		if refresh {
+			defer p.invalidate()
+		}
+
+		if cmd.Name != "" {
+			if res := cmd.Run(r.Context()); res.Error != "" {
+				return ActionResult{}, fmt.Errorf("%s: %s", res.Error, strings.TrimSpace(res.Stderr))
+			}
+		}
+
+		return ActionResult{Refresh: refresh}, nil
+	})
+}
+
+// pluginShortcut converts a SwiftBar shortcut (e.g. "CmdOrCtrl+shift+k") to the menu shortcut format.
+func pluginShortcut(key string) string {
+	r := strings.NewReplacer("cmdorctrl", "cmd", "optionoralt", "opt")
+	return r.Replace(strings.ToLower(key))
+}
+
+// pluginParams parses the space separated key=value parameters of a plugin line.
+// Values may be quoted with single or double quotes to include spaces.
+func pluginParams(s string) map[string]string {
+	params := map[string]string{}
+
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.TrimSpace(key)
+
+		var value string
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			end := strings.IndexByte(rest[1:], rest[0])
+			if end < 0 {
+				value, s = rest[1:], ""
+			} else {
+				value, s = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, s, _ = strings.Cut(rest, " ")
+		}
+
+		params[key] = value
+	}
+
+	return params
+}
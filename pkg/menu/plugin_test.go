@@ -0,0 +1,321 @@
+package menu
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPluginInterval(t *testing.T) {
+	tests := map[string]time.Duration{
+		"cpu.10s.sh":          10 * time.Second,
+		"/plugins/mail.5m.py": 5 * time.Minute,
+		"weather.1h.rb":       time.Hour,
+		"backup.2d.sh":        48 * time.Hour,
+		"plain.sh":            DefaultPluginInterval,
+		"bad.xs.sh":           DefaultPluginInterval,
+		"zero.0s.sh":          DefaultPluginInterval,
+	}
+
+	for path, want := range tests {
+		if got := PluginInterval(path); got != want {
+			t.Errorf("PluginInterval(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestPluginParse(t *testing.T) {
+	p := NewPlugin("/plugins/cpu.10s.sh")
+
+	items, err := p.parse(`CPU 12%
+Memory 40%
+---
+Open Activity Monitor | bash=/usr/bin/open param1=-a param2="Activity Monitor" terminal=false key=CmdOrCtrl+shift+a
+GitHub | href=https://github.com tooltip='Open GitHub'
+---
+Details
+--Load 1.2
+-----
+--Kill | bash=/bin/kill param1=1
+----Nested
+-------
+Hidden | dropdown=false
+Reload | refresh=true
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := &Menu{Title: "Test", Items: []Item{{Title: "Plugin", Items: items}}}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("expected plugin items to be valid, got: %v", err)
+	}
+
+	if len(items) != 7 {
+		t.Fatalf("expected 7 items, got %d: %+v", len(items), items)
+	}
+
+	want := []struct {
+		title   string
+		typ     ItemType
+		onClick string
+	}{
+		{"CPU 12%", ItemTypeHeader, ""},
+		{"Memory 40%", ItemTypeHeader, ""},
+		{"Open Activity Monitor", ItemTypeCallback, items[2].OnClick},
+		{"GitHub", ItemTypeLink, "https://github.com"},
+		{"", ItemTypeSeparator, ""},
+		{"Details", "", ""},
+		{"Reload", ItemTypeCallback, items[6].OnClick},
+	}
+	for i, w := range want {
+		if items[i].Title != w.title || items[i].Type != w.typ || items[i].OnClick != w.onClick {
+			t.Errorf("items[%d]: expected %q %q %q, got %q %q %q",
+				i, w.title, w.typ, w.onClick, items[i].Title, items[i].Type, items[i].OnClick)
+		}
+	}
+
+	for _, item := range []Item{items[2], items[6]} {
+		if !strings.HasPrefix(item.OnClick, PluginPathPrefix+"/cpu-") {
+			t.Errorf("expected callback path under the plugin, got %q", item.OnClick)
+		}
+	}
+	if items[2].OnClick == items[6].OnClick {
+		t.Errorf("expected actions to have different paths, got %q", items[2].OnClick)
+	}
+
+	if items[2].Shortcut != "cmd+shift+a" {
+		t.Errorf("expected SwiftBar shortcut to be converted, got %q", items[2].Shortcut)
+	}
+	if items[3].Description != "Open GitHub" {
+		t.Errorf("expected tooltip as description, got %q", items[3].Description)
+	}
+
+	details := items[5].Items
+	if len(details) != 3 || details[0].Type != ItemTypeHeader || details[1].Type != ItemTypeSeparator ||
+		details[2].Type != "" || details[2].Handler != nil {
+		t.Fatalf("expected nested items with the parent of a submenu turned into a plain submenu, got %+v", details)
+	}
+	if nested := details[2].Items; len(nested) != 2 || nested[0].Title != "Nested" || nested[1].Type != ItemTypeSeparator {
+		t.Errorf("expected second level of nesting, got %+v", nested)
+	}
+}
+
+func TestPluginActionPaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+	path := filepath.Join(dir, "actions.1h.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\ncat "+output+"\n"), 0o700); err != nil {
+		t.Fatalf("failed to write plugin: %v", err)
+	}
+
+	// Each action creates the file named after its title
+	lines := func(titles ...string) string {
+		out := "Actions\n---\n"
+		for _, title := range titles {
+			out += title + " | bash=/usr/bin/touch param1=" + filepath.Join(dir, title) + "\n"
+		}
+		return out
+	}
+
+	run := func(titles ...string) map[string]Item {
+		t.Helper()
+
+		if err := os.WriteFile(output, []byte(lines(titles...)), 0o600); err != nil {
+			t.Fatalf("failed to write output: %v", err)
+		}
+
+		items, err := NewPlugin(path).Items(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		byPath := map[string]Item{}
+		for _, item := range items[1:] {
+			byPath[item.OnClick] = item
+		}
+		return byPath
+	}
+
+	first := run("one", "two")
+	second := run("two", "three", "one")
+
+	for onClick, item := range first {
+		clicked, ok := second[onClick]
+		if !ok {
+			t.Fatalf("%s: expected path of %q to be kept, got %v", onClick, item.Title, second)
+		}
+
+		rec := httptest.NewRecorder()
+		clicked.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, onClick, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", onClick, rec.Code)
+		}
+
+		for _, title := range []string{"one", "two", "three"} {
+			_, err := os.Stat(filepath.Join(dir, title))
+			if ran := err == nil; ran != (title == item.Title) {
+				t.Errorf("%s: clicking %q, expected the command of %q to run: %t", onClick, item.Title, title, ran)
+			}
+		}
+		os.Remove(filepath.Join(dir, item.Title))
+	}
+
+	t.Run("plugins with the same name do not share paths", func(t *testing.T) {
+		cmd := Command{Name: "/bin/ls"}
+		a := NewPlugin("/a/cpu.sh").actionPath(nil, cmd, false, map[string]int{})
+		b := NewPlugin("/b/cpu.sh").actionPath(nil, cmd, false, map[string]int{})
+		if a == b {
+			t.Errorf("expected different paths, got %q", a)
+		}
+	})
+
+	t.Run("identical actions get distinct paths", func(t *testing.T) {
+		items, err := NewPlugin(path).parse(lines("same", "same"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if items[1].OnClick == items[2].OnClick {
+			t.Errorf("expected distinct paths, got %q", items[1].OnClick)
+		}
+	})
+}
+
+func TestPluginTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		want time.Duration
+	}{
+		{"plugin default", Item{Provider: NewPlugin("cpu.sh")}, DefaultCommandTimeout},
+		{"plugin timeout", Item{Provider: &Plugin{Path: "cpu.sh", Timeout: time.Minute}}, time.Minute},
+		{"item timeout", Item{Provider: NewPlugin("cpu.sh"), ProviderTimeout: time.Second}, time.Second},
+		{"other provider", Item{Provider: ProviderFunc(nil)}, DefaultProviderTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.item.providerTimeout(); got != tt.want {
+				t.Errorf("expected provider timeout %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPluginItems(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	dir := t.TempDir()
+	count := filepath.Join(dir, "count")
+	path := filepath.Join(dir, "counter.1h.sh")
+	script := "#!/bin/sh\necho run >> " + count + "\necho Counter\necho ---\necho \"Runs $(wc -l < " + count + " | tr -d ' ')\"\necho 'Again | refresh=true'\n"
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatalf("failed to write plugin: %v", err)
+	}
+
+	p := NewPlugin(path)
+	if p.Interval != time.Hour {
+		t.Fatalf("expected interval from file name, got %v", p.Interval)
+	}
+
+	items, err := p.Items(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items[1].Title != "Runs 1" {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+
+	t.Run("output is reused within the interval", func(t *testing.T) {
+		items, _ := p.Items(context.Background())
+		if items[1].Title != "Runs 1" {
+			t.Errorf("expected cached output, got %q", items[1].Title)
+		}
+	})
+
+	t.Run("refresh items run the plugin again", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		items[2].Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, items[2].OnClick, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+
+		var res ActionResult
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		if !res.Refresh || res.Version != ActionResultVersion {
+			t.Errorf("expected client to be asked to refresh, got %+v", res)
+		}
+
+		again, err := p.Items(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if again[1].Title != "Runs 2" {
+			t.Errorf("expected plugin to run again, got %q", again[1].Title)
+		}
+	})
+
+	t.Run("failing plugin is an error", func(t *testing.T) {
+		bad := filepath.Join(dir, "bad.sh")
+		if err := os.WriteFile(bad, []byte("#!/bin/sh\nexit 3\n"), 0o700); err != nil {
+			t.Fatalf("failed to write plugin: %v", err)
+		}
+		if _, err := NewPlugin(bad).Items(context.Background()); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestPluginRefresh(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	dir := t.TempDir()
+	count := filepath.Join(dir, "count")
+	path := filepath.Join(dir, "counter.sh")
+	script := "#!/bin/sh\necho run >> " + count + "\necho Counter\necho ---\necho \"Runs $(wc -l < " + count + " | tr -d ' ')\"\n"
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatalf("failed to write plugin: %v", err)
+	}
+
+	m := &Menu{
+		Title: "Test",
+		Items: []Item{
+			{Title: "Slow", Provider: &Plugin{Path: path, Interval: time.Hour}},
+			{Title: "Counter", Provider: &Plugin{Path: path, Interval: 20 * time.Millisecond}},
+		},
+	}
+
+	if d := m.refreshInterval(); d != 20*time.Millisecond {
+		t.Fatalf("expected the smallest plugin interval, got %v", d)
+	}
+
+	// Without RefreshInterval, plugins are run in the background on their own interval
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.refresh(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for m.events.revision.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rev := m.events.revision.Load(); rev < 2 {
+		t.Errorf("expected plugin changes to be published, got revision %d", rev)
+	}
+}
@@ -0,0 +1,271 @@
+package menu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultProviderTimeout is the maximum duration a provider has to compute its items
+	// when neither the item nor the provider set their own timeout.
+	DefaultProviderTimeout = 2 * time.Second
+)
+
+// Provider computes the sub-items of a menu item each time the menu is fetched.
+// Use it for items that come from live data (e.g. open pull requests or running containers).
+//
+// Returned items follow the same rules as static ones and may include callback, toggle
+// and radio items whose handlers are registered automatically. When a provider fails,
+// times out or returns invalid items, its last good result is served instead.
+type Provider interface {
+	// Items returns the current sub-items. The context is canceled after the provider timeout.
+	Items(ctx context.Context) ([]Item, error)
+}
+
+// timeoutProvider is implemented by providers that need another timeout than
+// DefaultProviderTimeout when the item does not set one (e.g. Plugin).
+type timeoutProvider interface {
+	timeout() time.Duration
+}
+
+// intervalProvider is implemented by providers that refresh on their own schedule (e.g. Plugin),
+// so that Run calls them in the background at least that often, even without RefreshInterval.
+type intervalProvider interface {
+	interval() time.Duration
+}
+
+// ProviderFunc is an adapter to allow the use of ordinary functions as providers.
+type ProviderFunc func(ctx context.Context) ([]Item, error)
+
+// Items calls f(ctx).
+func (f ProviderFunc) Items(ctx context.Context) ([]Item, error) {
+	return f(ctx)
+}
+
+// dynamic holds the last good result of every provider, keyed by the location of the
+// provider item in the tree. The zero value is ready to use.
+type dynamic struct {
+	mu         sync.Mutex
+	results    map[string]result
+	generation uint64 // incremented when the tree is swapped, invalidating in-flight results
+}
+
+// result is the last good result of a provider.
+type result struct {
+	id    string // ID of the provider item, the parent of the items
+	items []Item
+	raw   []byte // serialized items, used to detect changes
+}
+
+// located is a menu item along with its location in the tree and its ID.
+type located struct {
+	at       string
+	id       string
+	item     *Item
+	disabled bool // the item or one of its parents is disabled
+}
+
+// providers returns every item in the tree that has a provider.
+func providers(prefix, parent string, items []Item) []located {
+	var out []located
+	for i, id := range itemIDs(parent, items) {
+		at := fmt.Sprintf("%s[%d]", prefix, i)
+		if items[i].Provider != nil {
+			out = append(out, located{at: at, id: id, item: &items[i], disabled: items[i].Disabled})
+		}
+		for _, sub := range providers(at+".items", id, items[i].Items) {
+			sub.disabled = sub.disabled || items[i].Disabled
+			out = append(out, sub)
+		}
+	}
+
+	return out
+}
+
+// refresh calls every provider in the tree on the refresh interval until the context is canceled.
+// Clients are notified when any result differs from the previous one.
+// The interval is taken again after each refresh since the tree may have been swapped meanwhile;
+// while there is nothing to refresh, it is checked again every DefaultPluginInterval.
+func (m *Menu) refresh(ctx context.Context) {
+	for {
+		interval := m.refreshInterval()
+
+		wait := interval
+		if wait <= 0 {
+			wait = DefaultPluginInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if interval > 0 && m.resolve(ctx) {
+				m.Publish()
+			}
+		}
+	}
+}
+
+// refreshInterval returns how often providers are called in the background: the smallest of
+// RefreshInterval and the intervals of the providers refreshing on their own schedule (see
+// intervalProvider). Returns 0 if neither is set.
+func (m *Menu) refreshInterval() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	interval := m.RefreshInterval
+	for _, p := range providers("items", "", m.Items) {
+		ip, ok := p.item.Provider.(intervalProvider)
+		if !ok || p.disabled {
+			continue
+		}
+		if d := ip.interval(); d > 0 && (interval <= 0 || d < interval) {
+			interval = d
+		}
+	}
+
+	return interval
+}
+
+// resolve calls every provider in the tree concurrently, stores the good results
+// and rebuilds the router serving the handlers of the items they produced.
+// Returns true if any result differs from the previous one. It does not notify clients itself:
+// a client fetching the menu already gets the new results, and notifying it would make it
+// fetch the menu again, endlessly for providers whose results change on every call.
+func (m *Menu) resolve(ctx context.Context) bool {
+	m.mu.RLock()
+	list := providers("items", "", m.Items)
+
+	// Dynamic items must not reuse paths or IDs already taken by static items.
+	static := &validator{paths: map[string]string{}, byID: map[string]string{}}
+	static.items("items", m.Items)
+	static.ids("items", "", m.Items)
+
+	// Taken along with the providers so that results computed for a tree swapped meanwhile are discarded
+	m.dynamic.mu.Lock()
+	generation := m.dynamic.generation
+	m.dynamic.mu.Unlock()
+	m.mu.RUnlock()
+
+	if len(list) == 0 {
+		return false
+	}
+
+	results := make([][]Item, len(list))
+
+	var wg sync.WaitGroup
+	for i, p := range list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = m.provide(ctx, p, maps.Clone(static.paths), maps.Clone(static.byID))
+		}()
+	}
+	wg.Wait()
+
+	return m.store(generation, list, results)
+}
+
+// store saves the good provider results and rebuilds the router.
+// Results computed for a tree that has since been swapped are discarded.
+// Returns true if any result differs from the previous one.
+func (m *Menu) store(generation uint64, list []located, results [][]Item) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.dynamic.mu.Lock()
+	defer m.dynamic.mu.Unlock()
+
+	if generation != m.dynamic.generation {
+		return false
+	}
+
+	if m.dynamic.results == nil {
+		m.dynamic.results = map[string]result{}
+	}
+
+	changed := false
+	for i, p := range list {
+		if results[i] == nil {
+			continue
+		}
+
+		raw, err := json.Marshal(results[i])
+		if err != nil {
+			slog.Warn("failed to serialize provider result", "item", p.at, "error", err)
+			continue
+		}
+
+		prev, ok := m.dynamic.results[p.at]
+		if !ok || !bytes.Equal(prev.raw, raw) {
+			changed = true
+		}
+
+		m.dynamic.results[p.at] = result{id: p.id, items: results[i], raw: raw}
+	}
+
+	m.route()
+
+	return changed
+}
+
+// provide calls a single provider and validates its result.
+// Returns nil when the provider fails so that its last good result is kept.
+func (m *Menu) provide(ctx context.Context, p located, paths, ids map[string]string) []Item {
+	ctx, cancel := context.WithTimeout(ctx, p.item.providerTimeout())
+	defer cancel()
+
+	items, err := p.item.Provider.Items(ctx)
+	if err == nil {
+		v := &validator{paths: paths, byID: ids, dynamic: true}
+		v.items(p.at+".items", items)
+		v.ids(p.at+".items", p.id, items)
+		err = errors.Join(v.errs...)
+	}
+
+	if err != nil {
+		slog.Warn("provider failed, serving last good result",
+			"item", p.at,
+			"title", p.item.Title,
+			"error", err,
+		)
+		return nil
+	}
+
+	if items == nil {
+		items = []Item{}
+	}
+
+	return items
+}
+
+// providerTimeout returns the maximum duration the provider of the item has to compute its items:
+// the ProviderTimeout of the item, the timeout of the provider or DefaultProviderTimeout.
+func (i *Item) providerTimeout() time.Duration {
+	if i.ProviderTimeout > 0 {
+		return i.ProviderTimeout
+	}
+
+	if p, ok := i.Provider.(timeoutProvider); ok {
+		return p.timeout()
+	}
+
+	return DefaultProviderTimeout
+}
+
+// provided returns the last good result of the provider at the given location.
+func (m *Menu) provided(at string) []Item {
+	m.dynamic.mu.Lock()
+	defer m.dynamic.mu.Unlock()
+
+	return m.dynamic.results[at].items
+}
@@ -0,0 +1,162 @@
+package menu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProvider(t *testing.T) {
+	t.Run("items are computed on each fetch and their handlers are routed", func(t *testing.T) {
+		var (
+			calls   atomic.Int32
+			clicked atomic.Int32
+		)
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{
+					Title: "Live",
+					Provider: ProviderFunc(func(context.Context) ([]Item, error) {
+						calls.Add(1)
+						return []Item{
+							{
+								Title:   "Dynamic",
+								Type:    ItemTypeCallback,
+								OnClick: "/dynamic",
+								Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+									clicked.Add(1)
+									w.WriteHeader(http.StatusOK)
+								}),
+							},
+						}, nil
+					}),
+				},
+			},
+		}
+		if err := m.Validate(); err != nil {
+			t.Fatalf("expected valid menu, got: %v", err)
+		}
+		mux := serve(m)
+
+		var served Menu
+		get(t, mux, "/", &served)
+		get(t, mux, "/", &served)
+
+		if n := calls.Load(); n != 2 {
+			t.Errorf("expected provider to be called on each fetch, got %d calls", n)
+		}
+		if len(served.Items[0].Items) != 1 || served.Items[0].Items[0].Title != "Dynamic" {
+			t.Fatalf("expected provided items to be served, got %+v", served.Items[0].Items)
+		}
+
+		if code := post(t, mux, "/dynamic", nil); code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, code)
+		}
+		if clicked.Load() != 1 {
+			t.Error("expected dynamic handler to be called")
+		}
+
+		if code := post(t, mux, "/unknown", nil); code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, code)
+		}
+	})
+
+	t.Run("last good result is served on error and timeout", func(t *testing.T) {
+		var fail atomic.Int32 // 0: ok, 1: error, 2: hang
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{
+					Title:           "Live",
+					ProviderTimeout: 50 * time.Millisecond,
+					Provider: ProviderFunc(func(ctx context.Context) ([]Item, error) {
+						switch fail.Load() {
+						case 1:
+							return nil, errors.New("boom")
+						case 2:
+							<-ctx.Done()
+							return nil, ctx.Err()
+						}
+						return []Item{{Title: "Good", Type: ItemTypeLink, OnClick: "https://github.com"}}, nil
+					}),
+				},
+			},
+		}
+		mux := serve(m)
+
+		for _, mode := range []int32{0, 1, 2} {
+			fail.Store(mode)
+
+			var served Menu
+			get(t, mux, "/", &served)
+			if len(served.Items[0].Items) != 1 || served.Items[0].Items[0].Title != "Good" {
+				t.Errorf("mode %d: expected last good result, got %+v", mode, served.Items[0].Items)
+			}
+		}
+	})
+
+	t.Run("only background refreshes notify clients of changes", func(t *testing.T) {
+		var calls atomic.Int32
+		m := &Menu{
+			Title:           "Test",
+			RefreshInterval: 5 * time.Millisecond,
+			Items: []Item{
+				{
+					Title: "Live",
+					Provider: ProviderFunc(func(context.Context) ([]Item, error) {
+						return []Item{{Title: fmt.Sprintf("Call %d", calls.Add(1)), Type: ItemTypeHeader}}, nil
+					}),
+				},
+			},
+		}
+		mux := serve(m)
+
+		// A client fetching the menu already has the new result, notifying it would make it fetch again
+		var served Menu
+		for range 3 {
+			get(t, mux, "/", &served)
+		}
+		if rev := m.events.revision.Load(); rev != 0 {
+			t.Errorf("expected fetches not to publish changes, got revision %d", rev)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go m.refresh(ctx)
+
+		deadline := time.Now().Add(2 * time.Second)
+		for m.events.revision.Load() == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if m.events.revision.Load() == 0 {
+			t.Error("expected background refresh to publish changes")
+		}
+	})
+
+	t.Run("invalid results and static path conflicts are rejected", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "Static", Type: ItemTypeCallback, OnClick: "/static", Handler: noop},
+				{
+					Title: "Live",
+					Provider: ProviderFunc(func(context.Context) ([]Item, error) {
+						return []Item{{Title: "Clash", Type: ItemTypeCallback, OnClick: "/static", Handler: noop}}, nil
+					}),
+				},
+			},
+		}
+		mux := serve(m)
+
+		var served Menu
+		get(t, mux, "/", &served)
+		if len(served.Items[1].Items) != 0 {
+			t.Errorf("expected conflicting result to be rejected, got %+v", served.Items[1].Items)
+		}
+	})
+}
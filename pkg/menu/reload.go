@@ -0,0 +1,214 @@
+package menu
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultWatchInterval is how often WatchFile checks the menu file for changes.
+	DefaultWatchInterval = 2 * time.Second
+)
+
+// Swap validates the next menu and atomically replaces the served tree (Title, Description,
+// Version and Items) with it, along with the handlers of its items. The listener is not affected:
+// requests in flight complete against the previous tree and the following ones see the new one.
+// When next is invalid the current tree is kept and the validation error is returned.
+//
+// The state of toggle and radio items whose path did not change is kept. Provider results
+// are discarded and computed again on the next fetch. Clients are notified of the change.
+// The next menu must not be used after it has been swapped in.
+func (m *Menu) Swap(next *Menu) error {
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("invalid menu: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changes := diff(m.Items, next.Items)
+
+	m.Title = next.Title
+	m.Description = next.Description
+	m.Version = next.Version
+	m.Items = next.Items
+
+	m.dynamic.mu.Lock()
+	m.dynamic.generation++
+	m.dynamic.results = nil
+	m.route()
+	m.dynamic.mu.Unlock()
+
+	for _, c := range changes {
+		slog.Info("menu item "+c.kind, "item", c.item)
+	}
+	slog.Info("menu swapped", "title", m.Title, "changes", len(changes))
+
+	m.Publish()
+
+	return nil
+}
+
+// ReloadFile loads the menu file and swaps it in (see Load and Swap).
+// When the file does not set a version the current one is kept.
+// When the file cannot be loaded or is invalid the current tree is kept.
+func (m *Menu) ReloadFile(path string, reg Registry) error {
+	next, err := Load(path, reg)
+	if err != nil {
+		return err
+	}
+
+	if next.Version == "" {
+		m.mu.RLock()
+		next.Version = m.Version
+		m.mu.RUnlock()
+	}
+
+	return m.Swap(next)
+}
+
+// WatchFile reloads the menu from the file whenever its content changes or the process
+// receives SIGHUP, until the context is canceled. The file is polled on the given interval
+// (DefaultWatchInterval if not positive) so that it works on every platform and file system.
+// Reload errors are logged and the current tree is kept.
+func (m *Menu) WatchFile(ctx context.Context, path string, reg Registry, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, err := fingerprint(path)
+	if err != nil {
+		slog.Warn("failed to read menu file", "path", path, "error", err)
+	}
+
+	slog.Info("watching menu file", "path", path, "interval", interval)
+
+	reload := func(reason string) {
+		slog.Info("reloading menu", "path", path, "reason", reason)
+		if err := m.ReloadFile(path, reg); err != nil {
+			slog.Error("failed to reload menu, keeping current one", "path", path, "error", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			last, _ = fingerprint(path)
+			reload("SIGHUP")
+		case <-ticker.C:
+			sum, err := fingerprint(path)
+			if err != nil {
+				slog.Debug("failed to read menu file", "path", path, "error", err)
+				continue
+			}
+			if !bytes.Equal(sum, last) {
+				last = sum
+				reload("file changed")
+			}
+		}
+	}
+}
+
+// fingerprint returns the hash of the file content.
+func fingerprint(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(b)
+
+	return sum[:], nil
+}
+
+// change describes an item added, removed or changed between two trees.
+type change struct {
+	kind string // added, removed or changed
+	item string // title path of the item (e.g. "Tools > Deploy")
+}
+
+// diff returns the changes between two trees, matching items by their title path.
+func diff(prev, next []Item) []change {
+	before, after := &flat{values: map[string]string{}}, &flat{values: map[string]string{}}
+	before.add("", prev)
+	after.add("", next)
+
+	var changes []change
+	for _, key := range before.keys {
+		if _, ok := after.values[key]; !ok {
+			changes = append(changes, change{kind: "removed", item: key})
+		}
+	}
+
+	for _, key := range after.keys {
+		old, ok := before.values[key]
+		switch {
+		case !ok:
+			changes = append(changes, change{kind: "added", item: key})
+		case old != after.values[key]:
+			changes = append(changes, change{kind: "changed", item: key})
+		}
+	}
+
+	return changes
+}
+
+// flat is a tree flattened to the serialized form of every item, without its sub-items,
+// keyed by title path. Keys are kept in tree order.
+type flat struct {
+	keys   []string
+	values map[string]string
+}
+
+// add flattens the items located under the prefix title path.
+func (f *flat) add(prefix string, items []Item) {
+	for i, item := range items {
+		key := itemKey(prefix, i, &item)
+
+		children := item.Items
+		item.Items = nil
+		b, err := json.Marshal(item)
+		if err != nil {
+			b = []byte(err.Error())
+		}
+
+		if _, ok := f.values[key]; !ok {
+			f.keys = append(f.keys, key)
+		}
+		f.values[key] = string(b)
+
+		f.add(key, children)
+	}
+}
+
+// itemKey returns the title path of an item. Items without a title (e.g. separators)
+// are identified by their type and position instead.
+func itemKey(prefix string, i int, item *Item) string {
+	name := item.Title
+	if name == "" {
+		name = fmt.Sprintf("[%d:%s]", i, item.Type)
+	}
+
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + " > " + name
+}
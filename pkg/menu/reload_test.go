@@ -0,0 +1,176 @@
+package menu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSwap(t *testing.T) {
+	m := &Menu{
+		Title: "Before",
+		Items: []Item{
+			{Title: "Old", Type: ItemTypeCallback, OnClick: "/old", Handler: noop},
+		},
+	}
+	mux := serve(m)
+
+	if code := post(t, m.Handler(), "/old", nil); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+
+	invalid := &Menu{Title: "Invalid", Items: []Item{{Title: "Broken", Type: ItemTypeCallback, OnClick: "/broken"}}}
+	if err := m.Swap(invalid); err == nil {
+		t.Error("expected invalid menu to be rejected")
+	}
+
+	next := &Menu{
+		Title: "After",
+		Items: []Item{
+			{Title: "New", Type: ItemTypeCallback, OnClick: "/new", Handler: noop},
+		},
+	}
+	if err := m.Swap(next); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var served Menu
+	get(t, mux, "/", &served)
+	if served.Title != "After" || served.Items[0].Title != "New" {
+		t.Errorf("expected swapped menu, got %q with %+v", served.Title, served.Items)
+	}
+
+	if code := post(t, m.Handler(), "/new", nil); code != http.StatusOK {
+		t.Errorf("expected new route to be served, got %d", code)
+	}
+	if code := post(t, m.Handler(), "/old", nil); code != http.StatusNotFound {
+		t.Errorf("expected old route to be removed, got %d", code)
+	}
+}
+
+func TestSwapDiscardsInFlightResults(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	m := &Menu{
+		Title: "Before",
+		Items: []Item{
+			{Title: "Old", Provider: ProviderFunc(func(context.Context) ([]Item, error) {
+				close(started)
+				<-release
+				return []Item{{Title: "Stale", Type: ItemTypeCallback, OnClick: "/stale", Handler: noop}}, nil
+			})},
+		},
+	}
+
+	done := make(chan bool)
+	go func() { done <- m.resolve(context.Background()) }()
+	<-started
+
+	// The provider at the same position in the new tree must not get the result of the old one
+	next := &Menu{
+		Title: "After",
+		Items: []Item{
+			{Title: "New", Provider: ProviderFunc(func(context.Context) ([]Item, error) { return nil, errors.New("down") })},
+		},
+	}
+	if err := m.Swap(next); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(release)
+
+	if <-done {
+		t.Error("expected result computed for the previous tree to be discarded")
+	}
+	if items := m.provided("items[0]"); items != nil {
+		t.Errorf("expected no result for the new provider, got %+v", items)
+	}
+	if code := post(t, m.Handler(), "/stale", nil); code != http.StatusNotFound {
+		t.Errorf("expected stale route not to be served, got %d", code)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	prev := []Item{
+		{Title: "Keep", Type: ItemTypeLink, OnClick: "https://a.com"},
+		{Title: "Edit", Type: ItemTypeLink, OnClick: "https://b.com"},
+		{Title: "Drop", Type: ItemTypeLink, OnClick: "https://c.com"},
+	}
+	next := []Item{
+		{Title: "Keep", Type: ItemTypeLink, OnClick: "https://a.com"},
+		{Title: "Edit", Type: ItemTypeLink, OnClick: "https://changed.com"},
+		{Title: "Group", Items: []Item{{Title: "Add", Type: ItemTypeLink, OnClick: "https://d.com"}}},
+	}
+
+	got := diff(prev, next)
+	want := []change{
+		{kind: "removed", item: "Drop"},
+		{kind: "changed", item: "Edit"},
+		{kind: "added", item: "Group"},
+		{kind: "added", item: "Group > Add"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "menu.yaml")
+	write := func(title string) {
+		t.Helper()
+		content := "title: " + title + "\nitems:\n  - title: Noop\n    type: callback\n    onClick: /noop\n    handler: noop\n"
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write menu file: %v", err)
+		}
+	}
+
+	write("First")
+	m, err := Load(path, Registry{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.WatchFile(ctx, path, Registry{}, 10*time.Millisecond)
+
+	// title returns the title of the served menu.
+	title := func() string {
+		var served Menu
+		get(t, m.Handler(), "/", &served)
+		return served.Title
+	}
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if title() == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("expected title %q, got %q", want, title())
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	write("Second")
+	waitFor("Second")
+
+	// an invalid file keeps the current tree
+	if err := os.WriteFile(path, []byte("title: Third\nitems:\n  - title: Broken\n    type: callback\n"), 0o600); err != nil {
+		t.Fatalf("failed to write menu file: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := title(); got != "Second" {
+		t.Errorf("expected invalid file to be ignored, got title %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package menu
+
+import (
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// route rebuilds the router serving the handlers of every item in the tree,
+// static ones first and then those in the last good result of every provider.
+// The items of providers that are disabled, or under a disabled item, are rejected with 403.
+// When a path is used more than once the first item wins. Requests with another method
+// than the one of the item are answered with 405 and an Allow header by the router.
+// Must be called with the tree read lock and the dynamic mutex held.
+func (m *Menu) route() {
+	mux := http.NewServeMux()
+	seen := map[string]string{}
+
+	register := func(at string) func(pattern string, h http.Handler) {
+		return func(pattern string, h http.Handler) {
+			_, path, _ := strings.Cut(pattern, " ") // patterns are "METHOD /path"
+			if prev, ok := seen[path]; ok {
+				slog.Warn("duplicate item path, ignoring", "item", at, "path", path, "used_by", prev)
+				return
+			}
+			seen[path] = at
+			mux.Handle(pattern, h)
+		}
+	}
+
+	for i, id := range itemIDs("", m.Items) {
+		m.registerItem(id, &m.Items[i], register("items"))
+	}
+
+	disabled := map[string]bool{}
+	for _, p := range providers("items", "", m.Items) {
+		disabled[p.at] = p.disabled
+	}
+
+	for _, at := range slices.Sorted(maps.Keys(m.dynamic.results)) {
+		res := m.dynamic.results[at]
+		for i, id := range itemIDs(res.id, res.items) {
+			if disabled[at] {
+				registerDisabled(&res.items[i], register(at))
+				continue
+			}
+			m.registerItem(id, &res.items[i], register(at))
+		}
+	}
+
+	m.routes.Store(mux)
+}
+
+// serveRoutes routes a request to the handler of the item registered for its path,
+// building the router on first use.
+func (m *Menu) serveRoutes(w http.ResponseWriter, r *http.Request) {
+	mux := m.routes.Load()
+	if mux == nil {
+		m.mu.RLock()
+		m.dynamic.mu.Lock()
+		m.route()
+		m.dynamic.mu.Unlock()
+		m.mu.RUnlock()
+
+		mux = m.routes.Load()
+	}
+
+	mux.ServeHTTP(w, r)
+}
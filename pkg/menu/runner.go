@@ -2,8 +2,8 @@ package menu
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
-	"net/http"
 
 	"github.com/mchmarny/momd/pkg/logger"
 	"github.com/mchmarny/momd/pkg/server"
@@ -14,23 +14,44 @@ const (
 )
 
 // Run starts the menu server and blocks until the context is canceled or an error occurs.
-// It automatically registers all menu item handlers and the root menu handler.
+// It serves the menu, the handlers of all menu items, the menu change events, the jobs
+// and the usage of the items, whose metrics are also added to the server registry.
+// The menu is validated first and the server is not started if it is invalid.
+// Requests for hosts other than the loopback ones and cross-site browser requests are rejected
+// (see server.WithHostCheck), pass server.WithoutHostCheck to disable it.
 func (m *Menu) Run(ctx context.Context, opt ...server.Option) error {
 	logger.New(name, m.Version)
 	slog.Info("starting menu runner")
 
-	if opt == nil {
-		opt = []server.Option{}
+	if err := m.Validate(); err != nil {
+		return fmt.Errorf("invalid menu: %w", err)
 	}
 
+	// Protect against DNS rebinding by default, the options can add hosts or disable it
+	opt = append([]server.Option{server.WithHostCheck()}, opt...)
+
+	// Menu item handlers are served by the menu handler so they follow the tree when it is swapped
 	opt = append(opt,
 		server.WithHandler("/", m.Handler()),
+		server.WithHandler(EventsPath, m.EventsHandler()),
+		server.WithHandler(JobsPath, m.JobsHandler()),
+		server.WithHandler(JobsPath+"/", m.JobsHandler()),
+		server.WithHandler(StatsPath, m.StatsHandler()),
+		server.WithCollectors(&m.usage),
 	)
 
-	// Register all menu item handlers
-	m.RegisterHandlers(func(pattern string, h http.Handler) {
-		opt = append(opt, server.WithHandler(pattern, h))
-	})
+	m.usage.start()
+
+	// Disconnect event streams on shutdown, they would otherwise hold the server open,
+	// and cancel running jobs
+	go func() {
+		<-ctx.Done()
+		m.events.close()
+		m.jobs.close()
+	}()
+
+	// Call providers in the background on RefreshInterval or, for plugins, on their own interval
+	go m.refresh(ctx)
 
 	// Create and run the server
 	return server.New(opt...).Serve(ctx)
@@ -0,0 +1,161 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ModifierControl is the Control (⌃) key.
+	ModifierControl Modifier = "ctrl"
+	// ModifierOption is the Option (⌥) key.
+	ModifierOption Modifier = "opt"
+	// ModifierShift is the Shift (⇧) key.
+	ModifierShift Modifier = "shift"
+	// ModifierCommand is the Command (⌘) key.
+	ModifierCommand Modifier = "cmd"
+
+	// shortcutSeparator separates modifiers and the key in a shortcut string.
+	shortcutSeparator = "+"
+)
+
+// Modifier represents a keyboard modifier key.
+type Modifier string
+
+var (
+	// modifierOrder is the canonical modifier order used when formatting a shortcut (e.g. "cmd+ctrl+opt+shift+k").
+	modifierOrder = []Modifier{ModifierCommand, ModifierControl, ModifierOption, ModifierShift}
+
+	// modifierAliases maps every accepted modifier spelling to its canonical modifier.
+	modifierAliases = map[string]Modifier{
+		"ctrl":    ModifierControl,
+		"control": ModifierControl,
+		"opt":     ModifierOption,
+		"option":  ModifierOption,
+		"alt":     ModifierOption,
+		"shift":   ModifierShift,
+		"cmd":     ModifierCommand,
+		"command": ModifierCommand,
+	}
+
+	// namedKeys maps every accepted spelling of a non-printable key to its canonical name.
+	namedKeys = map[string]string{
+		"space":     "space",
+		"tab":       "tab",
+		"return":    "return",
+		"enter":     "return",
+		"escape":    "escape",
+		"esc":       "escape",
+		"delete":    "delete",
+		"backspace": "delete",
+		"up":        "up",
+		"down":      "down",
+		"left":      "left",
+		"right":     "right",
+		"home":      "home",
+		"end":       "end",
+		"pageup":    "pageup",
+		"pagedown":  "pagedown",
+		"plus":      "plus",
+	}
+)
+
+// Shortcut is a parsed keyboard shortcut (e.g. "cmd+shift+1").
+type Shortcut struct {
+	// Modifiers are the modifier keys in canonical order, without duplicates.
+	Modifiers []Modifier
+
+	// Key is the canonical name of the key: a single printable character
+	// (e.g. "1", "g", ","), a named key (e.g. "space", "return") or a function key (e.g. "f5").
+	Key string
+}
+
+// ParseShortcut parses a shortcut string such as "cmd+shift+g".
+// Parsing is case-insensitive, accepts modifier aliases (command, control, option, alt)
+// and key aliases (enter, esc, backspace), and returns the modifiers in canonical order.
+func ParseShortcut(s string) (Shortcut, error) {
+	var sc Shortcut
+
+	if strings.TrimSpace(s) == "" {
+		return sc, errors.New("empty shortcut")
+	}
+
+	parts := strings.Split(strings.ToLower(s), shortcutSeparator)
+	seen := map[Modifier]bool{}
+
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		last := i == len(parts)-1
+
+		if mod, ok := modifierAliases[part]; ok {
+			if seen[mod] {
+				return sc, fmt.Errorf("duplicate modifier %q", part)
+			}
+			seen[mod] = true
+			if last {
+				return sc, fmt.Errorf("missing key after modifier %q", part)
+			}
+			continue
+		}
+
+		if !last {
+			return sc, fmt.Errorf("unknown modifier %q", part)
+		}
+
+		key, err := parseKey(part)
+		if err != nil {
+			return sc, err
+		}
+		sc.Key = key
+	}
+
+	for _, mod := range modifierOrder {
+		if seen[mod] {
+			sc.Modifiers = append(sc.Modifiers, mod)
+		}
+	}
+
+	return sc, nil
+}
+
+// parseKey returns the canonical name of a shortcut key.
+func parseKey(k string) (string, error) {
+	if k == "" {
+		return "", errors.New("missing key")
+	}
+
+	if name, ok := namedKeys[k]; ok {
+		return name, nil
+	}
+
+	if isFunctionKey(k) {
+		return k, nil
+	}
+
+	if len(k) == 1 && k[0] > ' ' && k[0] < 0x7f {
+		return k, nil
+	}
+
+	return "", fmt.Errorf("unknown key %q", k)
+}
+
+// isFunctionKey reports whether k is one of the function keys f1 through f20.
+func isFunctionKey(k string) bool {
+	var n int
+	if _, err := fmt.Sscanf(k, "f%d", &n); err != nil {
+		return false
+	}
+
+	return fmt.Sprintf("f%d", n) == k && n >= 1 && n <= 20
+}
+
+// String returns the canonical form of the shortcut (e.g. "cmd+shift+g").
+func (s Shortcut) String() string {
+	parts := make([]string, 0, len(s.Modifiers)+1)
+	for _, mod := range s.Modifiers {
+		parts = append(parts, string(mod))
+	}
+
+	return strings.Join(append(parts, s.Key), shortcutSeparator)
+}
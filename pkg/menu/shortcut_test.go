@@ -0,0 +1,57 @@
+package menu
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseShortcut(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  string
+	}{
+		{in: "cmd+1", want: "cmd+1"},
+		{in: "Shift+Command+G", want: "cmd+shift+g"},
+		{in: "alt+ctrl+d", want: "ctrl+opt+d"},
+		{in: "cmd + enter", want: "cmd+return"},
+		{in: "opt+f12", want: "opt+f12"},
+		{in: "cmd+,", want: "cmd+,"},
+		{in: "", err: "empty shortcut"},
+		{in: "cmd+shfit+1", err: `unknown modifier "shfit"`},
+		{in: "cmd+shift", err: "missing key"},
+		{in: "cmd+cmd+1", err: "duplicate modifier"},
+		{in: "cmd+f21", err: `unknown key "f21"`},
+		{in: "cmd+foo", err: `unknown key "foo"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			sc, err := ParseShortcut(tt.in)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := sc.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestItemMarshalJSONNormalizesShortcut(t *testing.T) {
+	b, err := json.Marshal(Item{Title: "A", Type: ItemTypeLink, OnClick: "https://github.com", Shortcut: "Shift+Command+G"})
+	if err != nil {
+		t.Fatalf("failed to marshal item: %v", err)
+	}
+
+	if !strings.Contains(string(b), `"shortcut":"cmd+shift+g"`) {
+		t.Errorf("expected normalized shortcut, got %s", b)
+	}
+}
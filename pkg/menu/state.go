@@ -0,0 +1,213 @@
+package menu
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+const (
+	// checkedParam is the query parameter carrying the requested state of a toggle item.
+	checkedParam = "checked"
+
+	// valueParam is the query parameter carrying the chosen option of a radio group.
+	valueParam = "value"
+
+	// selectedField is the response field carrying the selected option of a radio group.
+	selectedField = "selected"
+)
+
+// state holds the server-side state of stateful menu items, keyed by their onClick path.
+// The zero value is ready to use.
+type state struct {
+	mu      sync.Mutex
+	toggles map[string]*toggle
+	radios  map[string]*radio
+}
+
+// toggle is the current state of a single toggle item.
+// Its mutex is held for the whole click so concurrent clicks are applied one at a time.
+type toggle struct {
+	mu      sync.Mutex
+	checked bool
+}
+
+// radio is the current selection of a single radio group.
+// Its mutex is held for the whole click so the previous option is deselected
+// and the new one selected atomically.
+type radio struct {
+	mu       sync.Mutex
+	selected string
+}
+
+// toggle returns the state of the toggle item at path, creating it with the initial value if needed.
+func (s *state) toggle(path string, initial bool) *toggle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.toggles == nil {
+		s.toggles = map[string]*toggle{}
+	}
+
+	t, ok := s.toggles[path]
+	if !ok {
+		t = &toggle{checked: initial}
+		s.toggles[path] = t
+	}
+
+	return t
+}
+
+// checked returns the current state of the toggle item at path,
+// or the initial value if the item has not been used yet.
+func (s *state) checked(path string, initial bool) bool {
+	t := s.toggle(path, initial)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.checked
+}
+
+// toggleHandler returns the HTTP handler for a toggle item.
+// The requested state is read from the "checked" query parameter; when it is absent
+// the current state is flipped. The item's OnToggle handler is called with the
+// requested state and the state is only stored when it succeeds.
+func (m *Menu) toggleHandler(item *Item) http.Handler {
+	path, initial, onToggle := item.OnClick, item.Checked, item.OnToggle
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := m.state.toggle(path, initial)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		next := !t.checked
+		if v := r.URL.Query().Get(checkedParam); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s value: %q", checkedParam, v))
+				return
+			}
+			next = b
+		}
+
+		if onToggle != nil {
+			if err := onToggle(r.Context(), next); err != nil {
+				slog.Error("toggle handler failed", "path", path, "checked", next, "error", err)
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+
+		changed := t.checked != next
+		t.checked = next
+		slog.Info("toggle state changed", "path", path, "checked", next)
+
+		if changed {
+			m.Publish()
+		}
+
+		writeJSON(w, http.StatusOK, map[string]bool{checkedParam: next})
+	})
+}
+
+// radio returns the state of the radio group at path, creating it with the initial selection if needed.
+func (s *state) radio(path, initial string) *radio {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.radios == nil {
+		s.radios = map[string]*radio{}
+	}
+
+	g, ok := s.radios[path]
+	if !ok {
+		g = &radio{selected: initial}
+		s.radios[path] = g
+	}
+
+	return g
+}
+
+// selected returns the value of the selected option of the radio group at path,
+// or the initial selection if the group has not been used yet.
+func (s *state) selected(path, initial string) string {
+	g := s.radio(path, initial)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.selected
+}
+
+// value returns the value identifying a radio group option.
+func (i *Item) value() string {
+	if i.Value != "" {
+		return i.Value
+	}
+
+	return i.Title
+}
+
+// initialValue returns the value of the initially selected option of a radio group:
+// the first checked option, or the first option when none is checked.
+func (i *Item) initialValue() string {
+	for j := range i.Items {
+		if i.Items[j].Checked {
+			return i.Items[j].value()
+		}
+	}
+
+	if len(i.Items) > 0 {
+		return i.Items[0].value()
+	}
+
+	return ""
+}
+
+// radioHandler returns the HTTP handler for a radio group.
+// The chosen option is read from the "value" query parameter and must match one of
+// the group options. The group's OnSelect handler is called with the chosen value
+// and the selection is only stored when it succeeds.
+func (m *Menu) radioHandler(item *Item) http.Handler {
+	path, initial, onSelect := item.OnClick, item.initialValue(), item.OnSelect
+
+	options := make(map[string]bool, len(item.Items))
+	for j := range item.Items {
+		options[item.Items[j].value()] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next := r.URL.Query().Get(valueParam)
+		if !options[next] {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s: %q", valueParam, next))
+			return
+		}
+
+		g := m.state.radio(path, initial)
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		if onSelect != nil {
+			if err := onSelect(r.Context(), next); err != nil {
+				slog.Error("select handler failed", "path", path, "value", next, "error", err)
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+
+		changed := g.selected != next
+		g.selected = next
+		slog.Info("radio selection changed", "path", path, "value", next)
+
+		if changed {
+			m.Publish()
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{selectedField: next})
+	})
+}
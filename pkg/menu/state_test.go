@@ -0,0 +1,229 @@
+package menu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestToggle(t *testing.T) {
+	t.Run("click updates state served by the menu", func(t *testing.T) {
+		var got []bool
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{
+					Title:   "DND",
+					Type:    ItemTypeToggle,
+					OnClick: "/dnd",
+					OnToggle: func(_ context.Context, checked bool) error {
+						got = append(got, checked)
+						return nil
+					},
+				},
+			},
+		}
+		mux := serve(m)
+
+		var resp map[string]bool
+		if code := post(t, mux, "/dnd?checked=true", &resp); code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+		}
+		if !resp["checked"] {
+			t.Errorf("expected checked response, got %v", resp)
+		}
+
+		var served Menu
+		get(t, mux, "/", &served)
+		if !served.Items[0].Checked {
+			t.Error("expected menu to reflect toggled state")
+		}
+
+		// no explicit state flips the current one
+		post(t, mux, "/dnd", &resp)
+		if resp["checked"] {
+			t.Errorf("expected unchecked response, got %v", resp)
+		}
+
+		if len(got) != 2 || !got[0] || got[1] {
+			t.Errorf("expected handler calls [true false], got %v", got)
+		}
+	})
+
+	t.Run("failed handler keeps state", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{
+					Title:    "VPN",
+					Type:     ItemTypeToggle,
+					OnClick:  "/vpn",
+					Checked:  true,
+					OnToggle: func(context.Context, bool) error { return errors.New("boom") },
+				},
+			},
+		}
+		mux := serve(m)
+
+		if code := post(t, mux, "/vpn?checked=false", nil); code != http.StatusInternalServerError {
+			t.Errorf("expected status %d, got %d", http.StatusInternalServerError, code)
+		}
+		if code := post(t, mux, "/vpn?checked=maybe", nil); code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, code)
+		}
+
+		var served Menu
+		get(t, mux, "/", &served)
+		if !served.Items[0].Checked {
+			t.Error("expected toggle to keep its state")
+		}
+	})
+
+	t.Run("concurrent clicks are serialized", func(t *testing.T) {
+		var (
+			mu     sync.Mutex
+			active int
+		)
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{
+					Title:   "Flip",
+					Type:    ItemTypeToggle,
+					OnClick: "/flip",
+					OnToggle: func(context.Context, bool) error {
+						mu.Lock()
+						active++
+						n := active
+						mu.Unlock()
+						defer func() {
+							mu.Lock()
+							active--
+							mu.Unlock()
+						}()
+						if n > 1 {
+							return errors.New("concurrent toggle")
+						}
+						return nil
+					},
+				},
+			},
+		}
+		mux := serve(m)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if code := post(t, mux, "/flip", nil); code != http.StatusOK {
+					t.Errorf("expected status %d, got %d", http.StatusOK, code)
+				}
+			}()
+		}
+		wg.Wait()
+
+		var served Menu
+		get(t, mux, "/", &served)
+		if served.Items[0].Checked {
+			t.Error("expected an even number of flips to leave the toggle unchecked")
+		}
+	})
+}
+
+func TestRadio(t *testing.T) {
+	newMenu := func(onSelect SelectHandler) *Menu {
+		return &Menu{
+			Title: "Test",
+			Items: []Item{
+				{
+					Title:    "Environment",
+					Type:     ItemTypeRadio,
+					OnClick:  "/env",
+					OnSelect: onSelect,
+					Items: []Item{
+						{Title: "Dev"},
+						{Title: "Staging", Value: "stg", Checked: true},
+						{Title: "Prod"},
+					},
+				},
+			},
+		}
+	}
+
+	// checked returns the values of the checked options served by the menu.
+	checked := func(t *testing.T, h http.Handler) []string {
+		t.Helper()
+
+		var served Menu
+		get(t, h, "/", &served)
+
+		var out []string
+		for _, opt := range served.Items[0].Items {
+			if opt.Checked {
+				out = append(out, opt.Value)
+			}
+		}
+
+		return out
+	}
+
+	t.Run("selection deselects other options", func(t *testing.T) {
+		var got []string
+		m := newMenu(func(_ context.Context, value string) error {
+			got = append(got, value)
+			return nil
+		})
+		if err := m.Validate(); err != nil {
+			t.Fatalf("expected valid menu, got: %v", err)
+		}
+		mux := serve(m)
+
+		if sel := checked(t, mux); len(sel) != 1 || sel[0] != "stg" {
+			t.Fatalf("expected initial selection [stg], got %v", sel)
+		}
+
+		var resp map[string]string
+		if code := post(t, mux, "/env?value=Prod", &resp); code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+		}
+		if resp["selected"] != "Prod" {
+			t.Errorf("expected selected Prod, got %v", resp)
+		}
+
+		if sel := checked(t, mux); len(sel) != 1 || sel[0] != "Prod" {
+			t.Errorf("expected selection [Prod], got %v", sel)
+		}
+
+		if len(got) != 1 || got[0] != "Prod" {
+			t.Errorf("expected handler calls [Prod], got %v", got)
+		}
+	})
+
+	t.Run("unknown option and failed handler keep selection", func(t *testing.T) {
+		m := newMenu(func(context.Context, string) error { return errors.New("boom") })
+		mux := serve(m)
+
+		if code := post(t, mux, "/env?value=qa", nil); code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, code)
+		}
+		if code := post(t, mux, "/env?value=Dev", nil); code != http.StatusInternalServerError {
+			t.Errorf("expected status %d, got %d", http.StatusInternalServerError, code)
+		}
+
+		if sel := checked(t, mux); len(sel) != 1 || sel[0] != "stg" {
+			t.Errorf("expected selection [stg], got %v", sel)
+		}
+	})
+
+	t.Run("validation rejects multiple checked options", func(t *testing.T) {
+		m := newMenu(func(context.Context, string) error { return nil })
+		m.Items[0].Items[0].Checked = true
+
+		if err := m.Validate(); err == nil {
+			t.Error("expected validation error")
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package menu
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/mchmarny/momd/pkg/server"
+)
+
+const (
+	// StatsPath is the server path of the usage report of the menu items (see Stats).
+	StatsPath = "/stats"
+)
+
+var (
+	clicksDesc = prometheus.NewDesc("menu_item_clicks_total",
+		"Number of clicks on a menu item.", []string{"item"}, nil)
+	errorsDesc = prometheus.NewDesc("menu_item_errors_total",
+		"Number of clicks on a menu item that failed.", []string{"item"}, nil)
+	durationDesc = prometheus.NewDesc("menu_item_click_duration_seconds_total",
+		"Time spent handling clicks on a menu item.", []string{"item"}, nil)
+)
+
+// Stats is the usage of the menu items since the server started, as served at StatsPath.
+type Stats struct {
+	// Since is when usage started to be recorded.
+	Since time.Time `json:"since"`
+
+	// Items is the usage of every item that calls back to the server, in the menu or clicked
+	// since the server started, most clicked first.
+	Items []ItemStats `json:"items"`
+
+	// Unused are the IDs of the items in the menu that have not been clicked, candidates for pruning.
+	Unused []string `json:"unused"`
+}
+
+// ItemStats is the usage of a single item.
+type ItemStats struct {
+	// ID identifies the item (see Item.ID).
+	ID string `json:"id"`
+
+	// Title of the item.
+	Title string `json:"title,omitempty"`
+
+	// Clicks is how many times the item was clicked.
+	Clicks uint64 `json:"clicks"`
+
+	// Errors is how many clicks failed (responded with a 4xx or 5xx status),
+	// not counting clicks dropped by the concurrency policy of the item.
+	Errors uint64 `json:"errors"`
+
+	// DurationMs is the total time spent handling the clicks, in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+
+	// LastClicked is when the item was last clicked, if it was.
+	LastClicked *time.Time `json:"lastClicked,omitempty"`
+}
+
+// usage records the clicks on menu items, keyed by item ID, so that it survives routes being rebuilt.
+// It is also a Prometheus collector of the per-item metrics. The zero value is ready to use.
+type usage struct {
+	mu    sync.Mutex
+	since time.Time
+	items map[string]*itemUsage
+}
+
+// itemUsage is the usage of a single item.
+type itemUsage struct {
+	title    string
+	clicks   uint64
+	errors   uint64
+	duration time.Duration
+	last     time.Time
+}
+
+// start marks the beginning of the recording, unless it has already started.
+func (u *usage) start() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.since.IsZero() {
+		u.since = time.Now().UTC()
+	}
+}
+
+// record adds a click on the item, taking d to handle.
+func (u *usage) record(id, title string, d time.Duration, failed bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.items == nil {
+		u.items = map[string]*itemUsage{}
+	}
+
+	iu, ok := u.items[id]
+	if !ok {
+		iu = &itemUsage{}
+		u.items[id] = iu
+	}
+
+	iu.title = title
+	iu.clicks++
+	iu.duration += d
+	iu.last = time.Now().UTC()
+	if failed {
+		iu.errors++
+	}
+}
+
+// report returns the usage of the recorded items and of the given items of the menu.
+func (u *usage) report(items []*Item) Stats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	s := Stats{Since: u.since, Items: []ItemStats{}, Unused: []string{}}
+	seen := map[string]bool{}
+
+	for _, item := range items {
+		if seen[item.ID] {
+			continue
+		}
+		seen[item.ID] = true
+
+		is := ItemStats{ID: item.ID, Title: item.Title}
+		if iu, ok := u.items[item.ID]; ok {
+			is = iu.stats(item.ID)
+		} else {
+			s.Unused = append(s.Unused, item.ID)
+		}
+		s.Items = append(s.Items, is)
+	}
+
+	// Items clicked before they were removed from the menu
+	for id, iu := range u.items {
+		if !seen[id] {
+			s.Items = append(s.Items, iu.stats(id))
+		}
+	}
+
+	slices.SortFunc(s.Items, func(a, b ItemStats) int {
+		return cmp.Or(cmp.Compare(b.Clicks, a.Clicks), cmp.Compare(a.ID, b.ID))
+	})
+	slices.Sort(s.Unused)
+
+	return s
+}
+
+// stats returns the usage of the item with the ID.
+func (iu *itemUsage) stats(id string) ItemStats {
+	last := iu.last
+	return ItemStats{
+		ID:          id,
+		Title:       iu.title,
+		Clicks:      iu.clicks,
+		Errors:      iu.errors,
+		DurationMs:  iu.duration.Milliseconds(),
+		LastClicked: &last,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (u *usage) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clicksDesc
+	ch <- errorsDesc
+	ch <- durationDesc
+}
+
+// Collect implements prometheus.Collector.
+func (u *usage) Collect(ch chan<- prometheus.Metric) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for id, iu := range u.items {
+		ch <- prometheus.MustNewConstMetric(clicksDesc, prometheus.CounterValue, float64(iu.clicks), id)
+		ch <- prometheus.MustNewConstMetric(errorsDesc, prometheus.CounterValue, float64(iu.errors), id)
+		ch <- prometheus.MustNewConstMetric(durationDesc, prometheus.CounterValue, iu.duration.Seconds(), id)
+	}
+}
+
+// count wraps the handler of the item with the ID to record its clicks.
+func (m *Menu) count(id string, item *Item, h http.Handler) http.Handler {
+	title := item.Title
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &server.StatusWriter{ResponseWriter: w}
+		start := time.Now()
+
+		h.ServeHTTP(sw, r)
+
+		failed := sw.Status() >= http.StatusBadRequest && w.Header().Get(ConcurrencyHeader) != decisionDropped
+		m.usage.record(id, title, time.Since(start), failed)
+	})
+}
+
+// StatsHandler returns an HTTP handler serving the usage of the menu items at GET StatsPath.
+func (m *Menu) StatsHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+StatsPath, func(w http.ResponseWriter, _ *http.Request) {
+		var items []*Item
+		walkCallbacks(m.view().Items, func(item *Item) {
+			items = append(items, item)
+		})
+
+		writeJSON(w, http.StatusOK, m.usage.report(items))
+	})
+
+	return mux
+}
+
+// walkCallbacks calls fn for every item that calls back to the server, radio options excluded.
+func walkCallbacks(items []Item, fn func(*Item)) {
+	for i := range items {
+		item := &items[i]
+		if item.callsBack() {
+			fn(item)
+		}
+		if item.Type != ItemTypeRadio {
+			walkCallbacks(item.Items, fn)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package menu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStats(t *testing.T) {
+	fail := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusInternalServerError, "boom")
+	})
+
+	m := &Menu{
+		Title: "Test",
+		Items: []Item{
+			{Title: "Hello", Type: ItemTypeCallback, OnClick: "/hello", Handler: noop},
+			{Title: "Broken", Type: ItemTypeCallback, OnClick: "/broken", Handler: fail},
+			{Title: "DND", Type: ItemTypeToggle, OnClick: "/dnd", OnToggle: func(context.Context, bool) error {
+				return errors.New("rejected")
+			}},
+			{Title: "Unused", Items: []Item{
+				{Title: "Never", Type: ItemTypeCallback, OnClick: "/never", Handler: noop},
+			}},
+			{Title: "GitHub", Type: ItemTypeLink, OnClick: "https://github.com"},
+		},
+	}
+	m.usage.start()
+
+	mux := serve(m)
+	mux.Handle(StatsPath, m.StatsHandler())
+
+	post(t, mux, "/hello", nil)
+	post(t, mux, "/hello", nil)
+	post(t, mux, "/broken", nil)
+	post(t, mux, "/dnd?checked=true", nil)
+
+	var s Stats
+	if code := get(t, mux, StatsPath, &s); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+
+	if s.Since.IsZero() {
+		t.Error("expected start time")
+	}
+
+	want := []ItemStats{
+		{ID: "hello", Title: "Hello", Clicks: 2},
+		{ID: "broken", Title: "Broken", Clicks: 1, Errors: 1},
+		{ID: "dnd", Title: "DND", Clicks: 1, Errors: 1},
+		{ID: "unused/never", Title: "Never"},
+	}
+	if len(s.Items) != len(want) {
+		t.Fatalf("expected %d items, got %+v", len(want), s.Items)
+	}
+	for i, w := range want {
+		got := s.Items[i]
+		if got.ID != w.ID || got.Title != w.Title || got.Clicks != w.Clicks || got.Errors != w.Errors {
+			t.Errorf("items[%d]: expected %+v, got %+v", i, w, got)
+		}
+		if (got.LastClicked != nil) != (w.Clicks > 0) {
+			t.Errorf("items[%d]: unexpected last click %v", i, got.LastClicked)
+		}
+	}
+
+	if !slices.Equal(s.Unused, []string{"unused/never"}) {
+		t.Errorf("expected unused/never to be unused, got %v", s.Unused)
+	}
+
+	t.Run("items removed from the menu are still reported", func(t *testing.T) {
+		m.Swap(&Menu{Title: "Test", Items: []Item{{Title: "New", Type: ItemTypeCallback, OnClick: "/new", Handler: noop}}})
+
+		var s Stats
+		get(t, mux, StatsPath, &s)
+
+		if len(s.Items) != 4 || s.Items[0].ID != "hello" || !slices.Equal(s.Unused, []string{"new"}) {
+			t.Errorf("unexpected stats: %+v", s)
+		}
+	})
+
+	t.Run("metrics are labeled by item", func(t *testing.T) {
+		reg := prometheus.NewPedanticRegistry()
+		reg.MustRegister(&m.usage)
+
+		err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP menu_item_clicks_total Number of clicks on a menu item.
+# TYPE menu_item_clicks_total counter
+menu_item_clicks_total{item="broken"} 1
+menu_item_clicks_total{item="dnd"} 1
+menu_item_clicks_total{item="hello"} 2
+# HELP menu_item_errors_total Number of clicks on a menu item that failed.
+# TYPE menu_item_errors_total counter
+menu_item_errors_total{item="broken"} 1
+menu_item_errors_total{item="dnd"} 1
+menu_item_errors_total{item="hello"} 0
+`), "menu_item_clicks_total", "menu_item_errors_total")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,329 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/mchmarny/momd/pkg/server"
+)
+
+// methods are the HTTP methods items can be called back with.
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Validate walks the menu tree and reports every problem it finds.
+// Each problem is prefixed with the path of the offending item
+// (e.g. "items[2].items[0]: callback without handler") and all of them
+// are returned together as a single joined error. Returns nil when the menu is valid.
+func (m *Menu) Validate() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	v := &validator{paths: map[string]string{}, byID: map[string]string{}}
+
+	if strings.TrimSpace(m.Title) == "" {
+		v.add("menu", "missing title")
+	}
+
+	v.items("items", m.Items)
+	v.ids("items", "", m.Items)
+
+	return errors.Join(v.errs...)
+}
+
+// validator accumulates problems found while walking the menu tree.
+type validator struct {
+	errs    []error
+	paths   map[string]string // callback path -> location of the item that registered it
+	byID    map[string]string // item ID -> location of the item
+	dynamic bool              // validating items produced by a provider
+}
+
+// add records a problem found at the given location in the tree.
+func (v *validator) add(at, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", at, fmt.Sprintf(format, args...)))
+}
+
+// items validates a list of sibling items located under prefix.
+// Shortcuts must be unique among siblings since they share the same menu level.
+func (v *validator) items(prefix string, items []Item) {
+	shortcuts := map[string]string{}
+
+	for i := range items {
+		at := fmt.Sprintf("%s[%d]", prefix, i)
+		v.item(at, &items[i])
+		v.shortcut(at, items[i].Shortcut, shortcuts)
+	}
+}
+
+// shortcut validates an item shortcut and checks it against the shortcuts
+// already used at the same menu level.
+func (v *validator) shortcut(at, s string, seen map[string]string) {
+	if s == "" {
+		return
+	}
+
+	sc, err := ParseShortcut(s)
+	if err != nil {
+		v.add(at, "invalid shortcut %q: %v", s, err)
+		return
+	}
+
+	key := sc.String()
+	if prev, ok := seen[key]; ok {
+		v.add(at, "duplicate shortcut %q (also used by %s)", key, prev)
+		return
+	}
+	seen[key] = at
+}
+
+// item validates a single item and recurses into its sub-items.
+func (v *validator) item(at string, item *Item) {
+	if item.Type == ItemTypeSeparator {
+		v.separator(at, item)
+		return
+	}
+
+	if strings.TrimSpace(item.Title) == "" {
+		v.add(at, "missing title")
+	}
+
+	if item.Checked && item.Type != ItemTypeToggle {
+		v.add(at, "only toggle items and radio options can be checked")
+	}
+
+	if item.Method != "" {
+		if !item.callsBack() {
+			v.add(at, "only callback, toggle and radio items can have a method")
+		} else if !slices.Contains(methods, item.method()) {
+			v.add(at, "unsupported method %q (expected one of %s)", item.Method, strings.Join(methods, ", "))
+		}
+	}
+
+	if item.Concurrency != "" {
+		if !item.Concurrency.valid() {
+			v.add(at, "unknown concurrency policy %q", item.Concurrency)
+		} else if item.Type != ItemTypeCallback {
+			v.add(at, "only callback items can have a concurrency policy")
+		}
+	}
+
+	if item.Type == ItemTypeRadio {
+		v.radio(at, item)
+		return
+	}
+
+	if item.Provider != nil {
+		v.provider(at, item)
+		return
+	}
+
+	if len(item.Items) > 0 {
+		if item.Type != "" {
+			v.add(at, "item with sub-items must not have a type (got %q)", item.Type)
+		}
+		if item.Handler != nil {
+			v.add(at, "item with sub-items must not have a handler")
+		}
+		v.items(at+".items", item.Items)
+		return
+	}
+
+	switch item.Type {
+	case ItemTypeCallback:
+		v.callback(at, item)
+	case ItemTypeLink:
+		v.link(at, item)
+	case ItemTypeHeader:
+		v.inert(at, item)
+	case ItemTypeToggle:
+		v.toggle(at, item)
+	case "":
+		v.add(at, "item has neither a type, sub-items nor a provider")
+	default:
+		v.add(at, "unknown item type %q", item.Type)
+	}
+}
+
+// callback validates a callback item: it needs either a handler or a job and a unique, routable server path.
+func (v *validator) callback(at string, item *Item) {
+	switch {
+	case item.Handler == nil && item.Job == nil:
+		v.add(at, "callback without handler")
+	case item.Handler != nil && item.Job != nil:
+		v.add(at, "callback must not have both a handler and a job")
+	}
+
+	v.path(at, item.OnClick)
+}
+
+// toggle validates a toggle item: it needs an OnToggle handler and a unique, routable server path.
+func (v *validator) toggle(at string, item *Item) {
+	if item.OnToggle == nil {
+		v.add(at, "toggle without onToggle handler")
+	}
+	if item.Handler != nil {
+		v.add(at, "toggle must not have a handler, use onToggle")
+	}
+
+	v.path(at, item.OnClick)
+}
+
+// radio validates a radio group: it needs an OnSelect handler, a unique, routable server path
+// and at least one option. Options are identified by unique values and at most one is checked.
+func (v *validator) radio(at string, item *Item) {
+	if item.OnSelect == nil {
+		v.add(at, "radio without onSelect handler")
+	}
+	if item.Handler != nil {
+		v.add(at, "radio must not have a handler, use onSelect")
+	}
+	if len(item.Items) == 0 {
+		v.add(at, "radio without options")
+	}
+
+	v.path(at, item.OnClick)
+
+	var (
+		values    = map[string]string{}
+		shortcuts = map[string]string{}
+		checked   string
+	)
+
+	for i := range item.Items {
+		opt := &item.Items[i]
+		oat := fmt.Sprintf("%s.items[%d]", at, i)
+
+		if strings.TrimSpace(opt.Title) == "" {
+			v.add(oat, "missing title")
+		}
+		if opt.Type != "" {
+			v.add(oat, "radio option must not have a type (got %q)", opt.Type)
+		}
+		if opt.OnClick != "" || opt.Handler != nil || len(opt.Items) > 0 {
+			v.add(oat, "radio option must not have onClick, a handler or sub-items")
+		}
+
+		v.shortcut(oat, opt.Shortcut, shortcuts)
+
+		if prev, ok := values[opt.value()]; ok {
+			v.add(oat, "duplicate radio option value %q (also used by %s)", opt.value(), prev)
+		}
+		values[opt.value()] = oat
+
+		if opt.Checked {
+			if checked != "" {
+				v.add(oat, "only one radio option can be checked (also checked: %s)", checked)
+			}
+			checked = oat
+		}
+	}
+}
+
+// provider validates an item whose sub-items are computed by a provider.
+func (v *validator) provider(at string, item *Item) {
+	if v.dynamic {
+		v.add(at, "items produced by a provider must not have a provider")
+	}
+	if item.Type != "" {
+		v.add(at, "item with a provider must not have a type (got %q)", item.Type)
+	}
+	if len(item.Items) > 0 {
+		v.add(at, "item with a provider must not have static sub-items")
+	}
+	if item.Handler != nil {
+		v.add(at, "item with a provider must not have a handler")
+	}
+}
+
+// path validates a server path used to route clicks back to the server.
+func (v *validator) path(at, p string) {
+	switch {
+	case p == "":
+		v.add(at, "callback without onClick path")
+		return
+	case !strings.HasPrefix(p, "/"):
+		v.add(at, "callback path %q must start with /", p)
+		return
+	case p == "/" || p == EventsPath || p == StatsPath || p == JobsPath || strings.HasPrefix(p, JobsPath+"/"):
+		v.add(at, "callback path %q is reserved for the menu", p)
+		return
+	case p == server.HealthPath || p == server.ReadinessPath || p == server.MetricsPath:
+		v.add(at, "callback path %q is reserved for the server", p)
+		return
+	}
+
+	if prev, ok := v.paths[p]; ok {
+		v.add(at, "duplicate callback path %q (also used by %s)", p, prev)
+		return
+	}
+	v.paths[p] = at
+
+	if err := checkPattern(p); err != nil {
+		v.add(at, "invalid callback path %q: %v", p, err)
+	}
+}
+
+// link validates a link item: it needs an absolute URL and no handler.
+func (v *validator) link(at string, item *Item) {
+	if item.Handler != nil {
+		v.add(at, "link must not have a handler")
+	}
+
+	if item.OnClick == "" {
+		v.add(at, "link without URL")
+		return
+	}
+
+	u, err := url.Parse(item.OnClick)
+	if err != nil {
+		v.add(at, "invalid link URL %q: %v", item.OnClick, err)
+		return
+	}
+
+	if u.Scheme == "" {
+		v.add(at, "link URL %q must be absolute (e.g. https://...)", item.OnClick)
+	}
+}
+
+// separator validates a separator item, which only carries its type.
+func (v *validator) separator(at string, item *Item) {
+	if item.Title != "" {
+		v.add(at, "separator must not have a title")
+	}
+	if len(item.Items) > 0 {
+		v.add(at, "separator must not have sub-items")
+	}
+
+	v.inert(at, item)
+}
+
+// inert validates an item that cannot be clicked: it must not carry anything that reacts to a click.
+func (v *validator) inert(at string, item *Item) {
+	if item.OnClick != "" {
+		v.add(at, "%s must not have onClick", item.Type)
+	}
+	if item.Handler != nil {
+		v.add(at, "%s must not have a handler", item.Type)
+	}
+	if item.Shortcut != "" {
+		v.add(at, "%s must not have a shortcut", item.Type)
+	}
+}
+
+// checkPattern reports whether the pattern can be registered with http.ServeMux,
+// which panics on malformed patterns instead of returning an error.
+func checkPattern(pattern string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	http.NewServeMux().Handle(pattern, http.NotFoundHandler())
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package menu
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// noop is a handler used by tests that only care about the menu structure.
+var noop = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestValidate(t *testing.T) {
+	t.Run("valid menu passes", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "Callback", Type: ItemTypeCallback, OnClick: "/cb", Handler: noop},
+				{Title: "Link", Type: ItemTypeLink, OnClick: "https://github.com"},
+				{
+					Title: "Submenu",
+					Items: []Item{
+						{Title: "Nested", Type: ItemTypeCallback, OnClick: "/nested", Handler: noop},
+					},
+				},
+			},
+		}
+
+		if err := m.Validate(); err != nil {
+			t.Errorf("expected valid menu, got: %v", err)
+		}
+	})
+
+	t.Run("reports every problem with its path", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "A", Type: ItemTypeCallback, OnClick: "/a", Handler: noop},
+				{Title: "B", Type: ItemTypeLink, OnClick: "not a url"},
+				{
+					Title: "Submenu",
+					Type:  ItemTypeCallback,
+					Items: []Item{
+						{Title: "C", Type: ItemTypeCallback, OnClick: "/c"},
+						{Title: "D", Type: ItemTypeCallback, OnClick: "/a", Handler: noop},
+					},
+				},
+			},
+		}
+
+		err := m.Validate()
+		if err == nil {
+			t.Fatal("expected validation error")
+		}
+
+		want := []string{
+			"items[1]: link URL",
+			"items[2]: item with sub-items must not have a type",
+			"items[2].items[0]: callback without handler",
+			`items[2].items[1]: duplicate callback path "/a" (also used by items[0])`,
+		}
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("expected error to contain %q, got:\n%v", w, err)
+			}
+		}
+	})
+
+	t.Run("rejects reserved and malformed paths", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "Root", Type: ItemTypeCallback, OnClick: "/", Handler: noop},
+				{Title: "Relative", Type: ItemTypeCallback, OnClick: "relative", Handler: noop},
+				{Title: "Malformed", Type: ItemTypeCallback, OnClick: "/{bad", Handler: noop},
+				{Title: "Health", Type: ItemTypeCallback, OnClick: "/healthz", Handler: noop},
+				{Title: "Ready", Type: ItemTypeCallback, OnClick: "/readyz", Handler: noop},
+				{Title: "Metrics", Type: ItemTypeCallback, OnClick: "/metrics", Handler: noop},
+			},
+		}
+
+		err := m.Validate()
+		if err == nil {
+			t.Fatal("expected validation error")
+		}
+
+		for _, w := range []string{
+			"items[0]: callback path", "items[1]: callback path", "items[2]: invalid callback path",
+			`items[3]: callback path "/healthz" is reserved for the server`,
+			`items[4]: callback path "/readyz" is reserved for the server`,
+			`items[5]: callback path "/metrics" is reserved for the server`,
+		} {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("expected error to contain %q, got:\n%v", w, err)
+			}
+		}
+	})
+}
+
+func TestValidateShortcuts(t *testing.T) {
+	t.Run("reports invalid and duplicate shortcuts per level", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "A", Type: ItemTypeCallback, OnClick: "/a", Handler: noop, Shortcut: "cmd+1"},
+				{Title: "B", Type: ItemTypeCallback, OnClick: "/b", Handler: noop, Shortcut: "command+1"},
+				{Title: "C", Type: ItemTypeCallback, OnClick: "/c", Handler: noop, Shortcut: "cmd+shfit+1"},
+				{
+					Title: "Submenu",
+					Items: []Item{
+						{Title: "D", Type: ItemTypeCallback, OnClick: "/d", Handler: noop, Shortcut: "cmd+1"},
+						{Title: "E", Type: ItemTypeCallback, OnClick: "/e", Handler: noop, Shortcut: "cmd+shift"},
+					},
+				},
+			},
+		}
+
+		err := m.Validate()
+		if err == nil {
+			t.Fatal("expected validation error")
+		}
+
+		want := []string{
+			`items[1]: duplicate shortcut "cmd+1" (also used by items[0])`,
+			`items[2]: invalid shortcut "cmd+shfit+1": unknown modifier "shfit"`,
+			`items[3].items[1]: invalid shortcut "cmd+shift": missing key`,
+		}
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("expected error to contain %q, got:\n%v", w, err)
+			}
+		}
+
+		if strings.Contains(err.Error(), "items[3].items[0]") {
+			t.Errorf("shortcut in a different menu level should not conflict, got:\n%v", err)
+		}
+	})
+}
+
+func TestValidateGrouping(t *testing.T) {
+	t.Run("separators and headers are valid without onClick", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Title: "Section", Type: ItemTypeHeader},
+				{Title: "A", Type: ItemTypeCallback, OnClick: "/a", Handler: noop},
+				{Type: ItemTypeSeparator},
+				{Title: "B", Type: ItemTypeLink, OnClick: "https://github.com"},
+			},
+		}
+
+		if err := m.Validate(); err != nil {
+			t.Errorf("expected valid menu, got: %v", err)
+		}
+	})
+
+	t.Run("separators and headers must not react to clicks", func(t *testing.T) {
+		m := &Menu{
+			Title: "Test",
+			Items: []Item{
+				{Type: ItemTypeSeparator, OnClick: "/sep", Handler: noop},
+				{Type: ItemTypeHeader, Shortcut: "cmd+h"},
+			},
+		}
+
+		err := m.Validate()
+		if err == nil {
+			t.Fatal("expected validation error")
+		}
+
+		want := []string{
+			"items[0]: separator must not have onClick",
+			"items[0]: separator must not have a handler",
+			"items[1]: missing title",
+			"items[1]: header must not have a shortcut",
+		}
+		for _, w := range want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("expected error to contain %q, got:\n%v", w, err)
+			}
+		}
+	})
+}
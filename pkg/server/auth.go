@@ -0,0 +1,137 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// TokenEnvVar is the environment variable conventionally used to pass the auth token to the server.
+	TokenEnvVar = "MOMD_TOKEN"
+
+	// tokenBytes is the number of random bytes in a generated token.
+	tokenBytes = 32
+
+	// bearerPrefix prefixes the token in the Authorization header.
+	bearerPrefix = "Bearer "
+)
+
+// WithAuthToken requires every request to carry the token in an "Authorization: Bearer <token>" header,
+// except requests to the public paths (e.g. "/healthz"). A public path ending with "/" also makes
+// every path below it public. Requests without the right token get 401 whether or not their
+// route exists, so that unauthenticated clients cannot discover routes.
+// An empty token disables authentication.
+//
+// Example:
+//
+//	token, err := server.LoadOrCreateToken("/path/to/token")
+//	srv := server.New(server.WithAuthToken(token, "/healthz"))
+func WithAuthToken(token string, public ...string) Option {
+	return func(s *server) {
+		if token == "" {
+			return
+		}
+
+		sum := sha256.Sum256([]byte(token))
+		s.middleware = append(s.middleware, func(next http.Handler) http.Handler {
+			return authenticate(sum, public, next)
+		})
+	}
+}
+
+// authenticate returns a handler that only passes requests with the token hashed to sum,
+// or to a public path, to next.
+func authenticate(sum [sha256.Size]byte, public []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublic(r.URL.Path, public) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Tokens are compared as hashes so that the comparison takes the same time whatever their length
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), bearerPrefix)
+		got := sha256.Sum256([]byte(token))
+		if !ok || subtle.ConstantTimeCompare(got[:], sum[:]) != 1 {
+			slog.Warn("unauthorized request", "method", r.Method, "url", r.URL.Path, "remote", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="momd"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isPublic reports whether the path is one of the public paths or below a public path ending with "/".
+func isPublic(path string, public []string) bool {
+	for _, p := range public {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadOrCreateToken returns the token stored in the file at path. If the file does not exist,
+// a random token is generated and written to it, readable by the current user only (0600).
+// An existing file that other users can access is rejected, since anyone who can read
+// the token can call the server.
+func LoadOrCreateToken(path string) (string, error) {
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if info.Mode().Perm()&0o077 != 0 {
+			return "", fmt.Errorf("token file %s must only be accessible by its owner (mode %v, expected 0600)", path, info.Mode().Perm())
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file %s: %w", path, err)
+		}
+
+		token := strings.TrimSpace(string(b))
+		if token == "" {
+			return "", fmt.Errorf("token file %s is empty", path)
+		}
+
+		return token, nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return "", fmt.Errorf("failed to check token file %s: %w", path, err)
+	}
+
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create token directory: %w", err)
+	}
+
+	// O_EXCL so that a file created concurrently (e.g. by another instance) is not overwritten
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(token + "\n"); err != nil {
+		return "", fmt.Errorf("failed to write token file %s: %w", path, err)
+	}
+
+	slog.Info("generated auth token", "path", path)
+
+	return token, nil
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithAuthToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h := New(
+		WithAuthToken("secret", "/healthz", "/public/"),
+		WithSimpleHealth(),
+		WithHandler("/action", ok),
+		WithHandler("/public/", ok),
+	).(*server).handler()
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"missing token", "/action", "", http.StatusUnauthorized},
+		{"wrong token", "/action", "Bearer wrong", http.StatusUnauthorized},
+		{"token without bearer scheme", "/action", "secret", http.StatusUnauthorized},
+		{"missing token on unknown route", "/unknown", "", http.StatusUnauthorized},
+		{"valid token", "/action", "Bearer secret", http.StatusOK},
+		{"valid token on unknown route", "/unknown", "Bearer secret", http.StatusNotFound},
+		{"public path", "/healthz", "", http.StatusOK},
+		{"below public prefix", "/public/page", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestLoadOrCreateToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "momd", "token")
+
+	t.Run("generates a private token file", func(t *testing.T) {
+		token, err := LoadOrCreateToken(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(token) != 2*tokenBytes {
+			t.Errorf("expected %d hex characters, got %q", 2*tokenBytes, token)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("expected token file: %v", err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+		}
+
+		again, err := LoadOrCreateToken(path)
+		if err != nil || again != token {
+			t.Errorf("expected the stored token to be reused, got %q, %v", again, err)
+		}
+	})
+
+	t.Run("rejects token files other users can read", func(t *testing.T) {
+		if err := os.Chmod(path, 0o644); err != nil {
+			t.Fatalf("failed to change mode: %v", err)
+		}
+		if _, err := LoadOrCreateToken(path); err == nil {
+			t.Error("expected error for readable token file")
+		}
+	})
+}
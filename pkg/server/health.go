@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCheckTimeout is the maximum duration of a single health or readiness check.
+	// A check that does not complete in time is reported as failed.
+	DefaultCheckTimeout = 2 * time.Second
+
+	// HealthPath is the path of the health (liveness) endpoint.
+	HealthPath = "/healthz"
+
+	// ReadinessPath is the path of the readiness endpoint.
+	ReadinessPath = "/readyz"
+)
+
+// CheckFunc is an adapter to allow the use of ordinary functions as health and readiness checks.
+//
+// Example:
+//
+//	srv := server.New(server.WithReadinessCheck("db", server.CheckFunc(db.PingContext)))
+type CheckFunc func(ctx context.Context) error
+
+// Healthy calls f(ctx).
+func (f CheckFunc) Healthy(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Ready calls f(ctx).
+func (f CheckFunc) Ready(ctx context.Context) error {
+	return f(ctx)
+}
+
+// check is a named health or readiness check.
+type check struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// checkResult is the outcome of a check as reported by the health endpoints.
+type checkResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// checkReport is the JSON body of the health endpoints.
+type checkReport struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks"`
+}
+
+// WithHealthCheck adds a named check to the health endpoint at /healthz.
+// All checks run concurrently on each request, each limited to the check timeout (see WithCheckTimeout).
+// Multiple checks can be added by calling this option multiple times.
+//
+// The endpoint returns:
+//   - 200 OK with body "ok" when every check passes
+//   - 503 Service Unavailable with a JSON breakdown of the failing checks otherwise
+//   - a JSON breakdown of every check, with either status, when called with ?verbose
+//
+// Example:
+//
+//	srv := server.New(server.WithHealthCheck("cache", cache))
+func WithHealthCheck(name string, c HealthChecker) Option {
+	return func(s *server) {
+		s.healthChecks = append(s.healthChecks, check{name: name, run: c.Healthy})
+	}
+}
+
+// WithReadinessCheck adds a named check to the readiness endpoint at /readyz.
+// It behaves like WithHealthCheck, see there for the responses.
+//
+// Example:
+//
+//	srv := server.New(server.WithReadinessCheck("db", server.CheckFunc(db.PingContext)))
+func WithReadinessCheck(name string, c ReadinessChecker) Option {
+	return func(s *server) {
+		s.readinessChecks = append(s.readinessChecks, check{name: name, run: c.Ready})
+	}
+}
+
+// WithCheckTimeout sets the maximum duration of each health and readiness check.
+// If not specified, DefaultCheckTimeout (2s) is used.
+func WithCheckTimeout(d time.Duration) Option {
+	return func(s *server) { s.checkTimeout = d }
+}
+
+// registerChecks registers the health and readiness endpoints that have checks.
+// The health endpoint is also registered by WithSimpleHealth, in which case it passes with no checks.
+func (s *server) registerChecks() {
+	if s.simpleHealth || len(s.healthChecks) > 0 {
+		s.mux.Handle(HealthPath, checkHandler(s.healthChecks, s.checkTimeout))
+	}
+
+	if len(s.readinessChecks) > 0 {
+		s.mux.Handle(ReadinessPath, checkHandler(s.readinessChecks, s.checkTimeout))
+	}
+}
+
+// checkHandler returns a handler that runs the checks and reports their outcome.
+func checkHandler(checks []check, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		results := runChecks(r.Context(), checks, timeout)
+
+		report := checkReport{Status: "ok", Checks: []checkResult{}}
+		failed := make([]checkResult, 0, len(results))
+		for _, res := range results {
+			if res.Error != "" {
+				failed = append(failed, res)
+			}
+		}
+
+		status := http.StatusOK
+		if len(failed) > 0 {
+			status = http.StatusServiceUnavailable
+			report.Status = "failed"
+			report.Checks = failed
+
+			slog.Warn("checks failed", "url", r.URL.Path, "failed", len(failed), "total", len(results))
+		}
+
+		if r.URL.Query().Has("verbose") {
+			report.Checks = results
+		} else if status == http.StatusOK {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			slog.Error("failed to write check report", "error", err)
+		}
+	})
+}
+
+// runChecks runs the checks concurrently and returns their results in the order of the checks.
+func runChecks(ctx context.Context, checks []check, timeout time.Duration) []checkResult {
+	results := make([]checkResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Go(func() {
+			start := time.Now()
+			err := runCheck(ctx, c, timeout)
+
+			results[i] = checkResult{Name: c.name, Status: "ok", Duration: time.Since(start).String()}
+			if err != nil {
+				results[i].Status = "failed"
+				results[i].Error = err.Error()
+			}
+		})
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runCheck runs a single check, failing it when it does not return within the timeout or panics.
+// A check that ignores its context is left to finish in the background.
+func runCheck(ctx context.Context, c check, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out after %v: %w", timeout, ctx.Err())
+	}
+}
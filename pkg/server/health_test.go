@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthChecks(t *testing.T) {
+	pass := CheckFunc(func(context.Context) error { return nil })
+	fail := CheckFunc(func(context.Context) error { return errors.New("database unreachable") })
+	slow := CheckFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	stuck := CheckFunc(func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	broken := CheckFunc(func(context.Context) error { panic("boom") })
+
+	serve := func(t *testing.T, h http.Handler, path string) (*httptest.ResponseRecorder, checkReport) {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var report checkReport
+		if rec.Header().Get("Content-Type") == "application/json" {
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatalf("failed to decode report: %v", err)
+			}
+		}
+
+		return rec, report
+	}
+
+	t.Run("passing checks return ok", func(t *testing.T) {
+		h := New(WithHealthCheck("a", pass), WithReadinessCheck("b", pass)).(*server).handler()
+
+		for _, path := range []string{HealthPath, ReadinessPath} {
+			rec, _ := serve(t, h, path)
+			if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+				t.Errorf("%s: expected 200 ok, got %d %q", path, rec.Code, rec.Body.String())
+			}
+		}
+	})
+
+	t.Run("failing checks are reported", func(t *testing.T) {
+		h := New(
+			WithCheckTimeout(50*time.Millisecond),
+			WithReadinessCheck("ok", pass),
+			WithReadinessCheck("db", fail),
+			WithReadinessCheck("slow", slow),
+			WithReadinessCheck("stuck", stuck),
+			WithReadinessCheck("broken", broken),
+		).(*server).handler()
+
+		start := time.Now()
+		rec, report := serve(t, h, ReadinessPath)
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("expected checks to run concurrently within the timeout, took %v", elapsed)
+		}
+
+		if rec.Code != http.StatusServiceUnavailable || report.Status != "failed" {
+			t.Fatalf("expected 503 failed, got %d %q", rec.Code, report.Status)
+		}
+
+		want := []string{"db", "slow", "stuck", "broken"}
+		if len(report.Checks) != len(want) {
+			t.Fatalf("expected %d failing checks, got %+v", len(want), report.Checks)
+		}
+		for i, name := range want {
+			if report.Checks[i].Name != name || report.Checks[i].Status != "failed" || report.Checks[i].Error == "" {
+				t.Errorf("unexpected result for %s: %+v", name, report.Checks[i])
+			}
+		}
+	})
+
+	t.Run("verbose reports every check", func(t *testing.T) {
+		h := New(WithHealthCheck("a", pass), WithHealthCheck("b", pass)).(*server).handler()
+
+		rec, report := serve(t, h, HealthPath+"?verbose")
+		if rec.Code != http.StatusOK || report.Status != "ok" || len(report.Checks) != 2 {
+			t.Errorf("expected 200 with 2 checks, got %d %+v", rec.Code, report)
+		}
+	})
+
+	t.Run("simple health without checks", func(t *testing.T) {
+		h := New(WithSimpleHealth(), WithHealthCheck("db", fail)).(*server).handler()
+
+		if rec, _ := serve(t, h, HealthPath); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected checks to be reported with simple health, got %d", rec.Code)
+		}
+
+		h = New(WithSimpleHealth()).(*server).handler()
+		if rec, _ := serve(t, h, HealthPath); rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+			t.Errorf("expected 200 ok, got %d %q", rec.Code, rec.Body.String())
+		}
+		if rec, _ := serve(t, h, ReadinessPath); rec.Code != http.StatusNotFound {
+			t.Errorf("expected no readiness endpoint without checks, got %d", rec.Code)
+		}
+	})
+}
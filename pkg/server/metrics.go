@@ -0,0 +1,168 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// MetricsPath is the path of the Prometheus metrics endpoint.
+	MetricsPath = "/metrics"
+
+	// unmatchedRoute labels requests that do not match any route, e.g. rejected before routing.
+	unmatchedRoute = "unmatched"
+)
+
+// WithPrometheusMetrics serves the metrics of the server registry in the Prometheus format at /metrics.
+// Every request is instrumented with the following metrics, labeled by the route pattern it matched
+// (e.g. "/jobs/{id}" rather than the requested path) to keep their cardinality bounded:
+//   - http_requests_total: number of requests, also by method and status code
+//   - http_request_duration_seconds: histogram of the request latency, also by method
+//   - http_requests_in_flight: number of requests being served
+//
+// Routes are those registered with the server: a handler that routes requests itself, such as the
+// menu handler registered at "/", has all its requests counted under its own pattern. The menu
+// exports per-item metrics instead (menu_item_clicks_total and the like, see menu.StatsHandler).
+//
+// The Go runtime and process collectors are registered as well.
+//
+// Example:
+//
+//	srv := server.New(server.WithPrometheusMetrics())
+func WithPrometheusMetrics() Option {
+	return func(s *server) {
+		if s.metrics != nil {
+			return
+		}
+
+		m := newHTTPMetrics(s.registry)
+		s.registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+
+		s.mux.Handle(MetricsPath, promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{Registry: s.registry}))
+		s.metrics = func(next http.Handler) http.Handler {
+			return m.instrument(s.mux, next)
+		}
+	}
+}
+
+// WithCollectors registers Prometheus collectors with the server registry, e.g. application metrics.
+// They are served along with the server metrics when WithPrometheusMetrics is used.
+//
+// Example:
+//
+//	srv := server.New(server.WithPrometheusMetrics(), server.WithCollectors(jobsTotal))
+func WithCollectors(cs ...prometheus.Collector) Option {
+	return func(s *server) { s.registry.MustRegister(cs...) }
+}
+
+// httpMetrics are the metrics recorded for every request.
+type httpMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+// newHTTPMetrics creates the request metrics and registers them with the registry.
+func newHTTPMetrics(reg prometheus.Registerer) *httpMetrics {
+	m := &httpMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Latency of HTTP requests by route and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests being served by route.",
+		}, []string{"route"}),
+	}
+
+	reg.MustRegister(m.requests, m.duration, m.inFlight)
+
+	return m
+}
+
+// instrument returns a handler that records the metrics of each request served by next.
+// The route is resolved with the mux up front so that it is known while the request is in flight,
+// even if the request is rejected before it is routed.
+func (m *httpMetrics) instrument(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := metricMethod(r.Method)
+
+		inFlight := m.inFlight.WithLabelValues(route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		sw := &StatusWriter{ResponseWriter: w}
+		start := time.Now()
+
+		next.ServeHTTP(sw, r)
+
+		m.duration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+		m.requests.WithLabelValues(route, method, strconv.Itoa(sw.Status())).Inc()
+	})
+}
+
+// metricMethod returns the method as a label value, folding non-standard methods together
+// so that clients cannot create arbitrary label values.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
+
+// StatusWriter records the status code written to the response, for handlers that report on
+// the responses of the handlers they wrap (e.g. metrics or usage statistics).
+// It unwraps to the underlying writer so that http.ResponseController can still flush event streams.
+type StatusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *StatusWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= http.StatusOK {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.
+func (w *StatusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying response writer.
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the status code of the response, 200 if none was written explicitly.
+func (w *StatusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
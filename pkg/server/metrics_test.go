@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithPrometheusMetrics(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	h := New(
+		WithPrometheusMetrics(),
+		WithPrometheusMetrics(),
+		WithHandler("/items/{id}", ok),
+	).(*server).handler()
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	serve(http.MethodPost, "/items/1")
+	serve(http.MethodPost, "/items/2")
+	serve("BREW", "/items/3")
+	serve(http.MethodGet, "/unknown")
+
+	rec := serve(http.MethodGet, MetricsPath)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`http_requests_total{code="202",method="POST",route="/items/{id}"} 2`,
+		`http_requests_total{code="202",method="OTHER",route="/items/{id}"} 1`,
+		`http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		`http_request_duration_seconds_count{method="POST",route="/items/{id}"} 2`,
+		`http_requests_in_flight{route="/metrics"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+
+	if strings.Contains(body, `route="/items/1"`) {
+		t.Error("expected requests to be labeled by route pattern, not path")
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// WithHostCheck protects the server against DNS rebinding and cross-site browser requests.
+// A request is rejected with 403 unless its Host header names the server by a loopback
+// name or address ("localhost", "127.0.0.1", "[::1]"), by the IP address it was received on,
+// or by one of the additional hosts, and, when it carries a port, that port is the one
+// the request was received on. Requests a browser marks as cross-site, through the
+// Sec-Fetch-Site header or an Origin that does not match the Host, are rejected as well.
+// Hosts can be added by calling this option multiple times.
+//
+// Example:
+//
+//	srv := server.New(server.WithHostCheck("mac.local"))
+func WithHostCheck(hosts ...string) Option {
+	return func(s *server) {
+		s.hostCheck = true
+		for _, h := range hosts {
+			s.allowedHosts = append(s.allowedHosts, strings.ToLower(h))
+		}
+	}
+}
+
+// WithoutHostCheck disables the checks enabled by WithHostCheck, e.g. to expose the server
+// behind a reverse proxy. Only use it when the server is protected by other means.
+func WithoutHostCheck() Option {
+	return func(s *server) {
+		s.hostCheck = false
+		s.allowedHosts = nil
+	}
+}
+
+// checkHost returns a handler that only passes requests addressed to an allowed host
+// and not sent cross-site by a browser to next.
+func checkHost(allowed []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		local, _ := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+
+		if !isAllowedHost(r.Host, local, allowed) {
+			slog.Warn("rejected request for unknown host", "host", r.Host, "url", r.URL.Path, "remote", r.RemoteAddr)
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		}
+
+		if isCrossSite(r) {
+			slog.Warn("rejected cross-site request", "origin", r.Header.Get("Origin"), "url", r.URL.Path, "remote", r.RemoteAddr)
+			http.Error(w, "cross-site request", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAllowedHost reports whether the Host header value names the server the request was received on.
+// The port is only checked for requests received over TCP.
+func isAllowedHost(host string, local net.Addr, allowed []string) bool {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		// No port, the default port of the scheme is implied
+		name, port = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), ""
+	}
+	name = strings.ToLower(name)
+
+	tcp, _ := local.(*net.TCPAddr)
+	if port != "" && tcp != nil && port != tcpPort(tcp) {
+		return false
+	}
+
+	if name == "localhost" || slices.Contains(allowed, name) {
+		return true
+	}
+
+	// IP literals cannot be rebound, but only loopback and the receiving address are expected
+	ip := net.ParseIP(name)
+	return ip != nil && (ip.IsLoopback() || (tcp != nil && ip.Equal(tcp.IP)))
+}
+
+// tcpPort returns the port of the address as a string.
+func tcpPort(a *net.TCPAddr) string {
+	_, port, _ := net.SplitHostPort(a.String())
+	return port
+}
+
+// isCrossSite reports whether a browser marked the request as coming from another site or origin.
+// Requests that are not sent by a browser (e.g. curl or the menu bar app) carry neither header.
+func isCrossSite(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return true
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	return err != nil || !strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithHostCheck(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h := New(
+		WithHostCheck("Mac.local"),
+		WithHandler("/action", ok),
+	).(*server).handler()
+
+	local := &net.TCPAddr{IP: net.ParseIP("192.168.1.2"), Port: 9876}
+
+	tests := []struct {
+		name    string
+		host    string
+		local   net.Addr
+		headers map[string]string
+		want    int
+	}{
+		{"localhost", "localhost:9876", local, nil, http.StatusOK},
+		{"ipv4 loopback", "127.0.0.1:9876", local, nil, http.StatusOK},
+		{"ipv6 loopback", "[::1]:9876", local, nil, http.StatusOK},
+		{"receiving address", "192.168.1.2:9876", local, nil, http.StatusOK},
+		{"allowed host", "mac.local:9876", local, nil, http.StatusOK},
+		{"no port", "localhost", local, nil, http.StatusOK},
+		{"unix socket", "localhost", &net.UnixAddr{Name: "/tmp/momd.sock", Net: "unix"}, nil, http.StatusOK},
+		{"rebound name", "evil.example.com:9876", local, nil, http.StatusForbidden},
+		{"other address", "10.0.0.1:9876", local, nil, http.StatusForbidden},
+		{"other port", "localhost:8080", local, nil, http.StatusForbidden},
+		{"same origin", "localhost:9876", local, map[string]string{"Origin": "http://localhost:9876", "Sec-Fetch-Site": "same-origin"}, http.StatusOK},
+		{"typed in browser", "localhost:9876", local, map[string]string{"Sec-Fetch-Site": "none"}, http.StatusOK},
+		{"cross-site fetch", "localhost:9876", local, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"same-site fetch", "localhost:9876", local, map[string]string{"Sec-Fetch-Site": "same-site"}, http.StatusForbidden},
+		{"foreign origin", "localhost:9876", local, map[string]string{"Origin": "https://evil.example.com"}, http.StatusForbidden},
+		{"other port origin", "localhost:9876", local, map[string]string{"Origin": "http://localhost:3000"}, http.StatusForbidden},
+		{"null origin", "localhost:9876", local, map[string]string{"Origin": "null"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/action", nil)
+			req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, tt.local))
+			req.Host = tt.host
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	t.Run("can be disabled", func(t *testing.T) {
+		h := New(WithHostCheck(), WithoutHostCheck(), WithHandler("/action", ok)).(*server).handler()
+
+		req := httptest.NewRequest(http.MethodGet, "/action", nil)
+		req.Host = "evil.example.com"
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+}
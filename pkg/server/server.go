@@ -4,10 +4,12 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io/fs"
 	"log"
 	"log/slog"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -86,6 +88,10 @@ type ReadinessChecker interface {
 type server struct {
 	mux             *http.ServeMux       // HTTP request multiplexer
 	port            int                  // Port to listen on
+	host            string               // Host to listen on, all interfaces if empty
+	addrs           []string             // Explicit listen addresses, override host and port
+	socket          string               // Unix domain socket path, replaces host and port
+	socketMode      fs.FileMode          // Permission of the socket file
 	readTimeout     time.Duration        // Maximum duration for reading requests
 	writeTimeout    time.Duration        // Maximum duration for writing responses
 	idleTimeout     time.Duration        // Maximum idle time for keep-alive connections
@@ -96,8 +102,19 @@ type server struct {
 	mu              sync.RWMutex         // Protects running state
 	running         bool                 // Indicates if server is currently running
 	registry        *prometheus.Registry // Prometheus registry for metrics
+	middleware      []middleware         // Wrap the mux, first added is outermost
+	hostCheck       bool                 // Reject requests for other hosts and cross-site requests
+	allowedHosts    []string             // Host names allowed in addition to loopback ones
+	simpleHealth    bool                 // Serve the health endpoint even without checks
+	healthChecks    []check              // Checks run by the health endpoint
+	readinessChecks []check              // Checks run by the readiness endpoint
+	checkTimeout    time.Duration        // Maximum duration of each check
+	metrics         middleware           // Records request metrics, wraps everything else
 }
 
+// middleware wraps a handler to process requests before (or instead of) it.
+type middleware func(http.Handler) http.Handler
+
 // TLSConfig contains the certificate and key file paths for TLS/HTTPS support.
 type TLSConfig struct {
 	CertFile string // Path to the TLS certificate file
@@ -114,6 +131,28 @@ func WithPort(port int) Option {
 	return func(s *server) { s.port = port }
 }
 
+// WithHost sets the host (name or IP address) the server listens on, on the configured port.
+// "localhost" listens on both the IPv4 (127.0.0.1) and IPv6 (::1) loopback addresses.
+// If not specified, the server listens on all interfaces.
+//
+// Example:
+//
+//	srv := server.New(server.WithHost("127.0.0.1"))
+func WithHost(host string) Option {
+	return func(s *server) { s.host = host }
+}
+
+// WithAddress adds listen addresses in "host:port" form (e.g. "127.0.0.1:9876" or "[::1]:9876").
+// The server accepts connections on all of them; when any address is set, WithHost and WithPort are ignored.
+// Multiple addresses can be added by passing several or calling this option multiple times.
+//
+// Example:
+//
+//	srv := server.New(server.WithAddress("127.0.0.1:9876", "[::1]:9876"))
+func WithAddress(addrs ...string) Option {
+	return func(s *server) { s.addrs = append(s.addrs, addrs...) }
+}
+
 // WithReadTimeout sets the maximum duration for reading the entire request.
 // This includes reading the request headers and body.
 // If not specified, DefaultReadTimeout (10s) is used.
@@ -164,7 +203,8 @@ func WithHandler(pattern string, handler http.Handler) Option {
 
 // WithSimpleHealth adds a simple health check endpoint at /healthz that always returns 200 OK.
 // This is suitable for stateless services or services that don't need complex health checks.
-// For services that need to verify dependencies, use WithHealthCheck instead.
+// For services that need to verify dependencies, use WithHealthCheck instead; when both are
+// used, the endpoint reports the added checks.
 //
 // The endpoint returns:
 //   - 200 OK with body "ok"
@@ -173,17 +213,11 @@ func WithHandler(pattern string, handler http.Handler) Option {
 //
 //	srv := NewServer(WithSimpleHealth())
 func WithSimpleHealth() Option {
-	return func(s *server) {
-		s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte("ok"))
-		})
-	}
+	return func(s *server) { s.simpleHealth = true }
 }
 
 // WithTLS configures the server to use TLS/HTTPS with the provided certificate and key files.
-// The server will call ListenAndServeTLS instead of ListenAndServe.
+// Every listener of the server is wrapped with TLS.
 //
 // Example:
 //
@@ -210,6 +244,7 @@ func WithTLS(cfg TLSConfig) Option {
 //   - IdleTimeout: 60s
 //   - ShutdownTimeout: 5s
 //   - MaxHeaderBytes: 1 MB
+//   - CheckTimeout: 2s
 //
 // Example:
 //
@@ -229,6 +264,7 @@ func New(opts ...Option) Server {
 		idleTimeout:     DefaultIdleTimeout,
 		shutdownTimeout: DefaultShutdownTimeout,
 		maxHeaderBytes:  DefaultMaxHeaderBytes,
+		checkTimeout:    DefaultCheckTimeout,
 		mux:             http.NewServeMux(),
 		registry:        reg,
 		errLog:          log.Default(),
@@ -238,8 +274,12 @@ func New(opts ...Option) Server {
 		opt(s)
 	}
 
+	s.registerChecks()
+
 	slog.Info("server initialized",
-		"port", s.port,
+		"addresses", s.addresses(),
+		"socket", s.socket,
+		"host_check", s.hostCheck,
 		"read_timeout", s.readTimeout,
 		"write_timeout", s.writeTimeout)
 
@@ -281,10 +321,100 @@ func (s *server) IsRunning() bool {
 	return s.running
 }
 
+// addresses returns the TCP addresses the server listens on.
+// A server listening on a Unix domain socket only listens on explicit addresses.
+func (s *server) addresses() []string {
+	if len(s.addrs) > 0 || s.socket != "" {
+		return s.addrs
+	}
+
+	port := strconv.Itoa(s.port)
+	if s.host == "localhost" {
+		return []string{net.JoinHostPort("127.0.0.1", port), net.JoinHostPort("::1", port)}
+	}
+
+	return []string{net.JoinHostPort(s.host, port)}
+}
+
+// listen binds every listen address and the Unix domain socket, if any, wrapping the listeners
+// with TLS when configured. If any of them cannot be bound, the listeners already bound are closed.
+func (s *server) listen() (listeners []net.Listener, err error) {
+	var tlsConfig *tls.Config
+	if s.tlsConfig != nil {
+		cert, err := tls.LoadX509KeyPair(s.tlsConfig.CertFile, s.tlsConfig.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	defer func() {
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+		}
+	}()
+
+	add := func(l net.Listener) {
+		if tlsConfig != nil {
+			l = tls.NewListener(l, tlsConfig)
+			slog.Info("starting TLS server", "addr", l.Addr().String())
+		} else {
+			slog.Info("starting server", "addr", l.Addr().String())
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	for _, addr := range s.addresses() {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return listeners, fmt.Errorf("failed to create listener on %s: %w", addr, err)
+		}
+		add(l)
+	}
+
+	if s.socket != "" {
+		l, err := s.listenUnix()
+		if err != nil {
+			return listeners, err
+		}
+		add(l)
+	}
+
+	return listeners, nil
+}
+
+// handler returns the mux wrapped in the middleware, the first added being the outermost,
+// so that it sees every request before it is routed. The host check, when enabled, wraps
+// all of it so that requests for other hosts are rejected before anything else, and
+// the metrics, when enabled, wrap the host check so that rejected requests are counted too.
+func (s *server) handler() http.Handler {
+	var h http.Handler = s.mux
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		h = s.middleware[i](h)
+	}
+
+	if s.hostCheck {
+		h = checkHost(s.allowedHosts, h)
+	}
+
+	if s.metrics != nil {
+		h = s.metrics(h)
+	}
+
+	return h
+}
+
 // Serve starts the HTTP server and blocks until the context is canceled or an error occurs.
 //
-// The server uses errgroup to manage two goroutines:
-//  1. Server goroutine: Runs the HTTP server (ListenAndServe or ListenAndServeTLS)
+// The server uses errgroup to manage its goroutines:
+//  1. Server goroutines: Run the HTTP server on each listen address (with TLS when configured)
 //  2. Shutdown goroutine: Waits for context cancellation and initiates graceful shutdown
 //
 // When the context is canceled (e.g., SIGTERM), the shutdown goroutine:
@@ -317,8 +447,7 @@ func (s *server) IsRunning() bool {
 //	}
 func (s *server) Serve(ctx context.Context) error {
 	srv := &http.Server{
-		Addr:           fmt.Sprintf(":%d", s.port),
-		Handler:        s.mux,
+		Handler:        s.handler(),
 		ReadTimeout:    s.readTimeout,
 		WriteTimeout:   s.writeTimeout,
 		IdleTimeout:    s.idleTimeout,
@@ -326,66 +455,37 @@ func (s *server) Serve(ctx context.Context) error {
 		ErrorLog:       s.errLog,
 	}
 
-	// Create listener first so we can set running=true only after socket is bound
-	var (
-		listener net.Listener
-		err      error
-	)
-
-	if s.tlsConfig != nil {
-		// For TLS, create a regular listener and wrap it with TLS
-		listener, err = net.Listen("tcp", srv.Addr)
-		if err != nil {
-			return fmt.Errorf("failed to create listener: %w", err)
-		}
-
-		// Load TLS certificate
-		cert, certErr := tls.LoadX509KeyPair(s.tlsConfig.CertFile, s.tlsConfig.KeyFile)
-		if certErr != nil {
-			listener.Close()
-			return fmt.Errorf("failed to load TLS certificate: %w", certErr)
-		}
-
-		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12,
-		}
-
-		listener = tls.NewListener(listener, tlsConfig)
-
-		slog.Info("starting TLS server", "addr", srv.Addr)
-	} else {
-		listener, err = net.Listen("tcp", srv.Addr)
-		if err != nil {
-			return fmt.Errorf("failed to create listener: %w", err)
-		}
-
-		slog.Info("starting server", "addr", srv.Addr)
+	// Create listeners first so we can set running=true only after every socket is bound
+	listeners, err := s.listen()
+	if err != nil {
+		return err
 	}
 
 	g, gCtx := errgroup.WithContext(ctx)
 
-	// Server goroutine
-	g.Go(func() error {
-		// Mark server as running AFTER socket is successfully bound
+	// Mark server as running AFTER every socket is successfully bound
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		// Mark server as not running when it stops
 		s.mu.Lock()
-		s.running = true
+		s.running = false
 		s.mu.Unlock()
+	}()
 
-		defer func() {
-			// Mark server as not running when it stops
-			s.mu.Lock()
-			s.running = false
-			s.mu.Unlock()
-		}()
-
-		// Serve using the pre-created listener
-		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
-			return fmt.Errorf("server error: %w", err)
-		}
+	// Server goroutines, one per listener
+	for _, listener := range listeners {
+		g.Go(func() error {
+			// Serve using the pre-created listener
+			if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+				return fmt.Errorf("server error: %w", err)
+			}
 
-		return nil
-	})
+			return nil
+		})
+	}
 
 	// Shutdown goroutine
 	g.Go(func() error {
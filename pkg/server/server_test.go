@@ -526,3 +526,86 @@ func TestIsRunning(t *testing.T) {
 		}
 	})
 }
+
+func TestListenAddresses(t *testing.T) {
+	t.Run("resolves addresses from options", func(t *testing.T) {
+		tests := []struct {
+			name string
+			opts []Option
+			want []string
+		}{
+			{"all interfaces by default", nil, []string{":9876"}},
+			{"host and port", []Option{WithHost("127.0.0.1"), WithPort(8080)}, []string{"127.0.0.1:8080"}},
+			{"ipv6 host", []Option{WithHost("::1")}, []string{"[::1]:9876"}},
+			{"localhost is both loopbacks", []Option{WithHost("localhost")}, []string{"127.0.0.1:9876", "[::1]:9876"}},
+			{"addresses override host and port", []Option{WithHost("0.0.0.0"), WithAddress("127.0.0.1:1"), WithAddress("[::1]:2")}, []string{"127.0.0.1:1", "[::1]:2"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got := New(tt.opts...).(*server).addresses()
+				if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Errorf("expected %v, got %v", tt.want, got)
+				}
+			})
+		}
+	})
+
+	t.Run("serves on every address", func(t *testing.T) {
+		ports := []int{getFreePort(t), getFreePort(t)}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		srv := New(
+			WithAddress(fmt.Sprintf("127.0.0.1:%d", ports[0]), fmt.Sprintf("127.0.0.1:%d", ports[1])),
+			WithSimpleHealth(),
+		)
+
+		done := make(chan error, 1)
+		go func() { done <- srv.Serve(ctx) }()
+
+		for _, port := range ports {
+			waitForServer(t, port)
+
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/healthz", port))
+			if err != nil {
+				t.Fatalf("failed to GET /healthz on port %d: %v", port, err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("expected status %d on port %d, got %d", http.StatusOK, port, resp.StatusCode)
+			}
+		}
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("expected graceful shutdown, got %v", err)
+		}
+	})
+
+	t.Run("fails without leaking listeners when an address cannot be bound", func(t *testing.T) {
+		free := fmt.Sprintf("127.0.0.1:%d", getFreePort(t))
+
+		taken, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		defer taken.Close()
+
+		srv := New(WithAddress(free, taken.Addr().String()))
+		if err := srv.Serve(context.Background()); err == nil {
+			t.Fatal("expected error for address in use")
+		}
+		if srv.IsRunning() {
+			t.Error("expected server not to be running")
+		}
+
+		l, err := net.Listen("tcp", free)
+		if err != nil {
+			t.Errorf("expected first address to be released: %v", err)
+		} else {
+			l.Close()
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultSocketMode is the permission of the socket file when WithUnixSocket is given no mode:
+	// only the user running the server can connect.
+	DefaultSocketMode fs.FileMode = 0o600
+
+	// staleSocketTimeout is how long to wait when probing an existing socket file for a live server.
+	staleSocketTimeout = 200 * time.Millisecond
+)
+
+// WithUnixSocket makes the server listen on a Unix domain socket at path instead of a TCP port,
+// so that only local processes allowed by the file permission (mode, DefaultSocketMode if 0)
+// can connect. A stale socket file left by a previous run is removed on start, and the socket
+// file is removed on shutdown. TCP addresses set with WithAddress are still listened on.
+// Keep the socket in a directory only the user can write to, so the file cannot be replaced.
+//
+// Example:
+//
+//	srv := server.New(server.WithUnixSocket("/path/to/momd.sock", 0o600))
+func WithUnixSocket(path string, mode fs.FileMode) Option {
+	return func(s *server) {
+		if mode == 0 {
+			mode = DefaultSocketMode
+		}
+		s.socket, s.socketMode = path, mode
+	}
+}
+
+// listenUnix binds the Unix domain socket, replacing a stale socket file and restricting its permission.
+func (s *server) listenUnix() (net.Listener, error) {
+	if err := removeStaleSocket(s.socket); err != nil {
+		return nil, err
+	}
+
+	l, err := listenSocket(s.socket, s.socketMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create listener on %s: %w", s.socket, err)
+	}
+
+	if err := os.Chmod(s.socket, s.socketMode); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to set socket %s permission: %w", s.socket, err)
+	}
+
+	return l, nil
+}
+
+// removeStaleSocket removes the socket file at path if no server is accepting connections on it.
+// Returns an error if the path is in use by a live server or is not a socket, which is never removed.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check socket %s: %w", path, err)
+	}
+
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("socket path %s exists and is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, staleSocketTimeout); err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is in use by another server", path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+
+	slog.Info("removed stale socket", "path", path)
+
+	return nil
+}
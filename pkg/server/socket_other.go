@@ -0,0 +1,14 @@
+//go:build !unix
+
+package server
+
+import (
+	"io/fs"
+	"net"
+)
+
+// listenSocket creates the Unix domain socket at path. Platforms without a umask
+// only restrict its permission once it is created.
+func listenSocket(path string, _ fs.FileMode) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,164 @@
+//go:build unix
+
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// socketPath returns a socket path short enough for the platform limit (104 bytes on macOS).
+func socketPath(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "momd")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return filepath.Join(dir, "momd.sock")
+}
+
+// waitForRunning waits for the server to report that it is running.
+func waitForRunning(t *testing.T, srv Server) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if srv.IsRunning() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("server did not start within 2 seconds")
+}
+
+func TestWithUnixSocket(t *testing.T) {
+	t.Run("serves on the socket and removes it on shutdown", func(t *testing.T) {
+		path := socketPath(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		srv := New(WithUnixSocket(path, 0), WithSimpleHealth())
+		if addrs := srv.(*server).addresses(); len(addrs) != 0 {
+			t.Errorf("expected no TCP addresses with a socket, got %v", addrs)
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- srv.Serve(ctx) }()
+		waitForRunning(t, srv)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("expected socket file: %v", err)
+		}
+		if info.Mode().Perm() != DefaultSocketMode {
+			t.Errorf("expected mode %v, got %v", DefaultSocketMode, info.Mode().Perm())
+		}
+
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		}}
+		resp, err := client.Get("http://localhost/healthz")
+		if err != nil {
+			t.Fatalf("failed to GET /healthz over the socket: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("expected graceful shutdown, got %v", err)
+		}
+		if srv.IsRunning() {
+			t.Error("expected server not to be running after shutdown")
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected socket file to be removed, got %v", err)
+		}
+	})
+
+	t.Run("socket is created with restrictive permission", func(t *testing.T) {
+		path := socketPath(t)
+
+		// Even under a permissive umask, the socket is never accessible to other users
+		old := syscall.Umask(0)
+		defer syscall.Umask(old)
+
+		l, err := listenSocket(path, DefaultSocketMode)
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		defer l.Close()
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("expected socket file: %v", err)
+		}
+		if info.Mode().Perm()&^DefaultSocketMode != 0 {
+			t.Errorf("expected mode at most %v before chmod, got %v", DefaultSocketMode, info.Mode().Perm())
+		}
+	})
+
+	t.Run("replaces a stale socket", func(t *testing.T) {
+		path := socketPath(t)
+
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		srv := New(WithUnixSocket(path, 0o660))
+		done := make(chan error, 1)
+		go func() { done <- srv.Serve(ctx) }()
+		waitForRunning(t, srv)
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("expected stale socket to be replaced, got %v", err)
+		}
+	})
+
+	t.Run("refuses a socket in use or a regular file", func(t *testing.T) {
+		path := socketPath(t)
+
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		defer l.Close()
+
+		if err := New(WithUnixSocket(path, 0)).Serve(context.Background()); err == nil || !strings.Contains(err.Error(), "in use") {
+			t.Errorf("expected socket in use error, got %v", err)
+		}
+
+		file := filepath.Join(filepath.Dir(path), "file")
+		if err := os.WriteFile(file, []byte("keep"), 0o600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if err := New(WithUnixSocket(file, 0)).Serve(context.Background()); err == nil || !strings.Contains(err.Error(), "not a socket") {
+			t.Errorf("expected not a socket error, got %v", err)
+		}
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("expected regular file to be kept: %v", err)
+		}
+	})
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package server
+
+import (
+	"io/fs"
+	"net"
+	"sync"
+	"syscall"
+)
+
+// umaskMu serializes the umask changes of listenSocket.
+var umaskMu sync.Mutex
+
+// listenSocket creates the Unix domain socket at path under a umask that leaves it at most mode,
+// so that it is never accessible to other users, even for the moment before it is chmod-ed.
+// The umask is process-wide: files created meanwhile by other goroutines get more restrictive permissions.
+func listenSocket(path string, mode fs.FileMode) (net.Listener, error) {
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+
+	old := syscall.Umask(int(^mode.Perm() & fs.ModePerm))
+	defer syscall.Umask(old)
+
+	return net.Listen("unix", path)
+}